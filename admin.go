@@ -0,0 +1,330 @@
+package logfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//Handler returns a http.Handler to inspect and change the standard filter at
+//runtime. See (*Filter).Handler.
+func Handler() http.Handler {
+	return stdFilters.Handler()
+}
+
+//Handler returns a http.Handler to inspect and change the filter at runtime.
+//It can be mounted on any mux and dispatches on the end of the request path:
+//	GET    .../rules                 lists the default level and rules.
+//	POST   .../rules                 adds or modifies a rule.
+//	DELETE .../rules?package=<name>  deletes a rule.
+//	PUT    .../default               sets the default level.
+//	GET    .../level?file=<path>     reports the effective level of a file.
+//	GET    .../expr                  reports the filter expression (see SetExpr).
+//	PUT    .../expr                  sets the filter expression.
+//	GET    .../callsites             lists the call sites (see CallSites).
+//	POST   .../callsites             sets the state of a call site.
+//
+//Rules are sent and received as JSON, the level can be a band written as
+//min..max (see Between) and an optional ttl makes the rule temporary:
+//	{"package":"github.com/acme/db","inclusive":true,"level":"Debug","ttl":"15m"}
+//
+//Message rules add "contains" or "regexp" and may have an empty package to
+//match all packages, they are deleted with the same query parameters.
+//
+//Call sites are shared by all filters, the state is one of default, enabled or
+//disabled:
+//	{"file":"/src/github.com/acme/db/pool.go","line":42,"state":"disabled"}
+func (f *Filter) Handler() http.Handler {
+	return &adminHandler{filter: f}
+}
+
+//adminHandler implements the http.Handler returned by (*Filter).Handler.
+type adminHandler struct {
+	filter *Filter
+}
+
+//adminRule is the JSON representation of a filter. Expires is only set for
+//temporary filters, which are created by sending a TTL (see For). Sample is
+//only set for sampled filters, as 1/n or a percentage optionally followed by
+//:callsite or :message (see Sample). Rate, Burst and Shared are only set for
+//rate limited filters (see Limit).
+type adminRule struct {
+	Package   string  `json:"package"`
+	Contains  string  `json:"contains,omitempty"`
+	Regexp    string  `json:"regexp,omitempty"`
+	Inclusive bool    `json:"inclusive"`
+	Level     string  `json:"level"`
+	TTL       string  `json:"ttl,omitempty"`
+	Expires   string  `json:"expires,omitempty"`
+	Sample    string  `json:"sample,omitempty"`
+	Rate      float64 `json:"rate,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+	Shared    bool    `json:"shared,omitempty"`
+}
+
+//adminRules is the JSON representation of the rules in a filter.
+type adminRules struct {
+	Default string      `json:"default"`
+	Rules   []adminRule `json:"rules"`
+}
+
+//adminExpr is the JSON representation of a filter expression.
+type adminExpr struct {
+	Expr string `json:"expr"`
+}
+
+//adminCallSite is the JSON representation of a call site.
+type adminCallSite struct {
+	File  string `json:"file"`
+	Line  int    `json:"line"`
+	Hits  uint64 `json:"hits"`
+	State string `json:"state"`
+}
+
+//adminLevel is the JSON representation of a level, optionally for a file.
+type adminLevel struct {
+	File  string `json:"file,omitempty"`
+	Level string `json:"level"`
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/rules") && r.Method == http.MethodGet:
+		h.writeRules(w)
+		return
+	case strings.HasSuffix(r.URL.Path, "/rules") && r.Method == http.MethodPost:
+		err = h.setRule(r)
+	case strings.HasSuffix(r.URL.Path, "/rules") && r.Method == http.MethodDelete:
+		err = h.deleteRule(r)
+	case strings.HasSuffix(r.URL.Path, "/default") && r.Method == http.MethodPut:
+		err = h.setDefault(r)
+	case strings.HasSuffix(r.URL.Path, "/level") && r.Method == http.MethodGet:
+		h.writeLevel(w, r.URL.Query().Get("file"))
+		return
+	case strings.HasSuffix(r.URL.Path, "/expr") && r.Method == http.MethodGet:
+		writeJSON(w, adminExpr{Expr: h.filter.Expr()})
+		return
+	case strings.HasSuffix(r.URL.Path, "/expr") && r.Method == http.MethodPut:
+		a := adminExpr{}
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			http.Error(w, fmt.Sprintf("logfilter: invalid expression: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.filter.SetExpr(a.Expr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, adminExpr{Expr: h.filter.Expr()})
+		return
+	case strings.HasSuffix(r.URL.Path, "/callsites") && r.Method == http.MethodGet:
+		h.writeCallSites(w)
+		return
+	case strings.HasSuffix(r.URL.Path, "/callsites") && r.Method == http.MethodPost:
+		if err := h.setCallSite(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.writeCallSites(w)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeRules(w)
+}
+
+//writeRules writes the current rules of the filter.
+func (h *adminHandler) writeRules(w http.ResponseWriter) {
+	a := adminRules{
+		Rules: []adminRule{},
+	}
+
+	for _, f := range h.filter.load() {
+		if f.key() == "" {
+			a.Default = f.level()
+			continue
+		}
+		ar := adminRule{
+			Package:   f.find,
+			Contains:  f.contains,
+			Inclusive: f.inclusive,
+			Level:     f.level(),
+		}
+		if f.re != nil {
+			ar.Regexp = f.re.String()
+		}
+		if !f.expires.IsZero() {
+			ar.Expires = f.expires.Format(time.RFC3339)
+		}
+		if f.sample != nil {
+			ar.Sample = f.sample.String()
+		}
+		if f.limit != nil {
+			ar.Rate, ar.Burst, ar.Shared = f.limit.rate, int(f.limit.burst), f.limit.shared
+		}
+		a.Rules = append(a.Rules, ar)
+	}
+
+	writeJSON(w, a)
+}
+
+//setRule adds or modifies the rule in the request body.
+func (h *adminHandler) setRule(r *http.Request) error {
+	a := adminRule{}
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		return fmt.Errorf("logfilter: invalid rule: %v", err)
+	}
+
+	b, err := a.filter()
+	if err != nil {
+		return err
+	}
+
+	if err := b.setLevel(a.Level); err != nil {
+		return fmt.Errorf("logfilter: invalid rule: %v", err)
+	}
+
+	var ttl time.Duration
+	if a.TTL != "" {
+		if ttl, err = time.ParseDuration(a.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("logfilter: invalid rule: invalid ttl %q", a.TTL)
+		}
+	}
+
+	fs := h.filter.add(b, []string{a.Package})
+	if ttl > 0 {
+		fs.For(ttl)
+	}
+	return nil
+}
+
+//deleteRule deletes the rule named by the package, contains and regexp query
+//parameters.
+func (h *adminHandler) deleteRule(r *http.Request) error {
+	q := r.URL.Query()
+	b, err := adminRule{
+		Package:  q.Get("package"),
+		Contains: q.Get("contains"),
+		Regexp:   q.Get("regexp"),
+	}.filter()
+	if err != nil {
+		return err
+	}
+
+	h.filter.Remove(b.key())
+	return nil
+}
+
+//filter returns a filter for the package and message condition of the rule.
+func (a adminRule) filter() (filter, error) {
+	f := newFilter(a.Package, a.Inclusive, Undefined)
+	f.contains = a.Contains
+
+	if a.Regexp != "" {
+		re, err := regexp.Compile(a.Regexp)
+		if err != nil {
+			return f, fmt.Errorf("logfilter: invalid rule: %v", err)
+		}
+		f.re = re
+	}
+
+	s, err := parseSample(a.Sample)
+	if err != nil {
+		return f, fmt.Errorf("logfilter: invalid rule: %v", err)
+	}
+	f.sample = s
+
+	switch {
+	case a.Rate < 0:
+		return f, fmt.Errorf("logfilter: invalid rule: invalid rate %g", a.Rate)
+	case a.Rate > 0:
+		f.limit = newLimiter(a.Rate, a.Burst, a.Shared)
+	}
+
+	if f.key() == "" {
+		return f, fmt.Errorf("logfilter: invalid rule: missing package name")
+	}
+	return f, nil
+}
+
+//setDefault sets the default level from the request body.
+func (h *adminHandler) setDefault(r *http.Request) error {
+	a := adminLevel{}
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		return fmt.Errorf("logfilter: invalid level: %v", err)
+	}
+
+	l, err := specLevel(a.Level)
+	if err != nil {
+		return fmt.Errorf("logfilter: invalid level: %v", err)
+	}
+
+	h.filter.Default(l)
+	return nil
+}
+
+//writeLevel writes the lowest level written out for the file.
+func (h *adminHandler) writeLevel(w http.ResponseWriter, file string) {
+	writeJSON(w, adminLevel{
+		File:  file,
+		Level: LevelToString(h.filter.EffectiveLevel(file)),
+	})
+}
+
+//writeCallSites writes the call sites.
+func (h *adminHandler) writeCallSites(w http.ResponseWriter) {
+	a := []adminCallSite{}
+
+	for _, cs := range CallSites() {
+		a = append(a, adminCallSite{
+			File:  cs.File,
+			Line:  cs.Line,
+			Hits:  cs.Hits,
+			State: cs.State.String(),
+		})
+	}
+
+	writeJSON(w, a)
+}
+
+//setCallSite sets the state of the call site in the request body.
+func (h *adminHandler) setCallSite(r *http.Request) error {
+	a := adminCallSite{}
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		return fmt.Errorf("logfilter: invalid call site: %v", err)
+	}
+
+	if a.File == "" || a.Line <= 0 {
+		return fmt.Errorf("logfilter: invalid call site: missing file or line")
+	}
+
+	switch strings.ToLower(a.State) {
+	case "enabled":
+		EnableCallSite(a.File, a.Line)
+	case "disabled":
+		DisableCallSite(a.File, a.Line)
+	case "default":
+		ResetCallSite(a.File, a.Line)
+	default:
+		return fmt.Errorf("logfilter: invalid call site: unknown state %q", a.State)
+	}
+	return nil
+}
+
+//writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,64 @@
+package logfilter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestHandler(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Warning)
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/logfilter/", f.Handler())
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+		code   int
+		out    string
+	}{
+		{"GET", "/debug/logfilter/rules", "", http.StatusOK, `{"default":"Warning","rules":[]}`},
+		{"POST", "/debug/logfilter/rules", `{"package":"acme/db","inclusive":true,"level":"debug"}`, http.StatusOK, `{"default":"Warning","rules":[{"package":"acme/db","inclusive":true,"level":"Debug"}]}`},
+		{"POST", "/debug/logfilter/rules", `{"package":"acme/db","inclusive":true,"level":"loud"}`, http.StatusBadRequest, ""},
+		{"GET", "/debug/logfilter/level?file=/src/acme/db/db.go", "", http.StatusOK, `{"file":"/src/acme/db/db.go","level":"Debug"}`},
+		{"PUT", "/debug/logfilter/default", `{"level":"error"}`, http.StatusOK, `{"default":"Error","rules":[{"package":"acme/db","inclusive":true,"level":"Debug"}]}`},
+		{"DELETE", "/debug/logfilter/rules?package=acme/db", "", http.StatusOK, `{"default":"Error","rules":[]}`},
+		{"GET", "/debug/logfilter/level?file=/src/acme/db/db.go", "", http.StatusOK, `{"file":"/src/acme/db/db.go","level":"Error"}`},
+		{"POST", "/debug/logfilter/rules", `{"package":"acme/web","inclusive":true,"level":"trace","ttl":"forever"}`, http.StatusBadRequest, ""},
+		{"GET", "/debug/logfilter/unknown", "", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != test.code {
+			t.Errorf("%s %s expected status %d, actual %d", test.method, test.url, test.code, rec.Code)
+			continue
+		}
+
+		if test.out != "" && strings.TrimSpace(rec.Body.String()) != test.out {
+			t.Errorf("%s %s Mismatch Expected:\"%s\" Actual:\"%s\"\n", test.method, test.url, test.out, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestHandlerTTL(t *testing.T) {
+	f := logfilter.NewFilter()
+	h := f.Handler()
+
+	req := httptest.NewRequest("POST", "/rules", strings.NewReader(`{"package":"acme/db","inclusive":true,"level":"trace","ttl":"1h"}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"expires":"`) {
+		t.Errorf("Expected temporary rule with expiry, actual %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,89 @@
+package logfilter
+
+import (
+	"runtime"
+	"strings"
+)
+
+//thisPackage is the import path of this package, its frames are skipped when
+//looking for the caller.
+var thisPackage = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	p, _ := splitFuncName(runtime.FuncForPC(pc).Name())
+	return p
+}()
+
+//callerDepth is the number of frames looked at for the caller, enough to skip
+//those of the log package and this package.
+const callerDepth = 8
+
+//caller returns the frame of the function which logged the line being
+//written, skipping the frames of the log package and this package. It is
+//called for every line so the frames are looked up without allocating, unless
+//a call was inlined.
+func caller() runtime.Frame {
+	var pcs [callerDepth]uintptr
+	n := runtime.Callers(3, pcs[:])
+
+	for _, pc := range pcs[:n] {
+		fn := runtime.FuncForPC(pc - 1)
+		if fn == nil {
+			break
+		}
+
+		//An inlined function has the entry of the function it is inlined in,
+		//CallersFrames is needed to tell them apart.
+		name := fn.Name()
+		if outer := runtime.FuncForPC(fn.Entry()); outer == nil || outer.Name() != name {
+			return callerFrames(append([]uintptr(nil), pcs[:n]...))
+		}
+
+		if isCaller(name) {
+			file, line := fn.FileLine(pc - 1)
+			return runtime.Frame{PC: pc, Func: fn, Function: name, File: file, Line: line, Entry: fn.Entry()}
+		}
+	}
+	return runtime.Frame{}
+}
+
+//callerFrames implements caller using CallersFrames, which allows for inlined
+//functions.
+func callerFrames(pcs []uintptr) runtime.Frame {
+	frames := runtime.CallersFrames(pcs)
+
+	for {
+		f, more := frames.Next()
+		if isCaller(f.Function) {
+			return f
+		}
+		if !more {
+			return runtime.Frame{}
+		}
+	}
+}
+
+//isCaller returns true if the function isn't in the log package, the runtime
+//or this package.
+func isCaller(function string) bool {
+	p, _ := splitFuncName(function)
+	return p != "" && p != "log" && p != "runtime" && p != thisPackage
+}
+
+//splitFuncName splits a fully qualified function name
+//(i.e. github.com/acme/db.(*Pool).Acquire) into its import path
+//(github.com/acme/db) and function name ((*Pool).Acquire).
+//The dots of the last segment of the import path are escaped as %2e in the
+//name (i.e. gopkg.in/yaml%2ev2.Marshal).
+func splitFuncName(name string) (string, string) {
+	s := strings.LastIndex(name, "/") + 1
+	d := strings.Index(name[s:], ".")
+	if d < 0 {
+		return "", name
+	}
+
+	p := name[:s+d]
+	if strings.Contains(p, "%2e") {
+		p = strings.Replace(p, "%2e", ".", -1)
+	}
+	return p, name[s+d+1:]
+}
@@ -0,0 +1,143 @@
+package logfilter
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+//CallSiteState is the state of a call site, which decides if its lines are
+//written out regardless of the filter rules.
+type CallSiteState int32
+
+//Call site states.
+const (
+	CallSiteDefault  CallSiteState = iota // lines are filtered as normal.
+	CallSiteEnabled                       // lines are always written out.
+	CallSiteDisabled                      // lines are never written out.
+)
+
+//String returns the name of the state (i.e. "enabled").
+func (s CallSiteState) String() string {
+	switch s {
+	case CallSiteEnabled:
+		return "enabled"
+	case CallSiteDisabled:
+		return "disabled"
+	}
+	return "default"
+}
+
+//CallSite is a file:line location which has logged through a Logger.
+type CallSite struct {
+	File  string
+	Line  int
+	Hits  uint64
+	State CallSiteState
+}
+
+//CallSites returns every call site which has logged a line, or had its state
+//set, sorted by file and line.
+func CallSites() []CallSite {
+	return callSites.list()
+}
+
+//EnableCallSite writes out every line logged from the call site regardless of
+//the filter rules.
+func EnableCallSite(file string, line int) {
+	callSites.get(file, line).setState(CallSiteEnabled)
+}
+
+//DisableCallSite stops any line logged from the call site being written out
+//regardless of the filter rules.
+func DisableCallSite(file string, line int) {
+	callSites.get(file, line).setState(CallSiteDisabled)
+}
+
+//ResetCallSite returns the call site to being filtered by the filter rules.
+func ResetCallSite(file string, line int) {
+	callSites.get(file, line).setState(CallSiteDefault)
+}
+
+//callSiteKey identifies a call site.
+type callSiteKey struct {
+	file string
+	line int
+}
+
+//callSite holds the hit count and state of a call site.
+type callSite struct {
+	hits  uint64
+	state int32
+}
+
+func (s *callSite) setState(st CallSiteState) {
+	atomic.StoreInt32(&s.state, int32(st))
+}
+
+//callSiteRegistry records the call sites which have logged lines.
+type callSiteRegistry struct {
+	sites sync.Map // callSiteKey -> *callSite
+}
+
+var callSites = &callSiteRegistry{}
+
+//get returns the call site, creating it if it hasn't logged before.
+func (r *callSiteRegistry) get(file string, line int) *callSite {
+	k := callSiteKey{file: file, line: line}
+	if s, ok := r.sites.Load(k); ok {
+		return s.(*callSite)
+	}
+
+	s, _ := r.sites.LoadOrStore(k, &callSite{})
+	return s.(*callSite)
+}
+
+//state returns the state of the call site without recording a hit.
+func (r *callSiteRegistry) state(file string, line int) CallSiteState {
+	s, ok := r.sites.Load(callSiteKey{file: file, line: line})
+	if !ok {
+		return CallSiteDefault
+	}
+	return CallSiteState(atomic.LoadInt32(&s.(*callSite).state))
+}
+
+//allow records the hit on the call site of the line and returns true if the
+//line is written out, and whether the filter decided it. The call site state
+//takes precedence over the filter.
+func (r *callSiteRegistry) allow(l *LogLine, filter func(*LogLine) bool) (bool, bool) {
+	s := r.get(l.File, l.Line)
+	atomic.AddUint64(&s.hits, 1)
+
+	switch CallSiteState(atomic.LoadInt32(&s.state)) {
+	case CallSiteEnabled:
+		return true, false
+	case CallSiteDisabled:
+		return false, false
+	}
+	return filter == nil || filter(l), true
+}
+
+//list returns the call sites sorted by file and line.
+func (r *callSiteRegistry) list() []CallSite {
+	cs := []CallSite{}
+
+	r.sites.Range(func(k, v interface{}) bool {
+		s := v.(*callSite)
+		cs = append(cs, CallSite{
+			File:  k.(callSiteKey).file,
+			Line:  k.(callSiteKey).line,
+			Hits:  atomic.LoadUint64(&s.hits),
+			State: CallSiteState(atomic.LoadInt32(&s.state)),
+		})
+		return true
+	})
+
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].File != cs[j].File {
+			return cs[i].File < cs[j].File
+		}
+		return cs[i].Line < cs[j].Line
+	})
+	return cs
+}
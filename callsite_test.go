@@ -0,0 +1,78 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestCallSites(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.Default(logfilter.Warning)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	logAt := func(m string) {
+		lg.Println(m)
+	}
+
+	//The registry is shared by the process, so the hits are counted from what
+	//it held before, which is more than nothing when the test is repeated.
+	findSite := func() logfilter.CallSite {
+		for _, cs := range logfilter.CallSites() {
+			if strings.HasSuffix(cs.File, "callsite_test.go") {
+				return cs
+			}
+		}
+		return logfilter.CallSite{}
+	}
+	before := findSite().Hits
+
+	logAt("Debug: Message")
+	logAt("Warning: Message")
+
+	site := findSite()
+	if site.Hits-before != 2 || site.State != logfilter.CallSiteDefault {
+		t.Fatalf("Call site expected %d hits %s, actual %d hits %s", 2, logfilter.CallSiteDefault, site.Hits-before, site.State)
+	}
+
+	//Enabled call sites are written regardless of the rules.
+	b.Reset()
+	logfilter.EnableCallSite(site.File, site.Line)
+	logAt("Debug: Message")
+
+	if b.String() != "Debug: Message\n" {
+		t.Errorf("Enabled Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Debug: Message\n", b.String())
+	}
+
+	//Disabled call sites are never written.
+	b.Reset()
+	logfilter.DisableCallSite(site.File, site.Line)
+	logAt("Warning: Message")
+
+	if b.String() != "" {
+		t.Errorf("Disabled Mismatch Expected:\"%s\" Actual:\"%s\"\n", "", b.String())
+	}
+
+	//Changing the state from the handler.
+	req := httptest.NewRequest("POST", "/callsites", strings.NewReader(`{"file":"`+site.File+`","line":`+strconv.Itoa(site.Line)+`,"state":"default"}`))
+	rec := httptest.NewRecorder()
+	logfilter.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Setting call site state expected status %d, actual %d", http.StatusOK, rec.Code)
+	}
+
+	b.Reset()
+	logAt("Warning: Message")
+
+	if b.String() != "Warning: Message\n" {
+		t.Errorf("Default Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: Message\n", b.String())
+	}
+}
@@ -1,9 +1,13 @@
 package logfilter
 
 import (
+	"fmt"
 	"io"
 	"log"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -131,6 +135,7 @@ func StdParser(m string) (Level, string) {
 	c := strings.Index(m, ":")
 
 	// Our Smallest possible level is Info so if it's less than 3 it's not following the standard convention.
+	// Shorter aliases (i.e. ERR) are only read in brackets by SqrParser.
 	if c > 3 {
 		ls := m[:c]
 		r := m[c+1:]
@@ -144,15 +149,16 @@ func StdParser(m string) (Level, string) {
 	return Undefined, m
 }
 
-//SqrParser square convention parser
+//SqrParser square convention parser, the brackets start the message so
+//brackets later in the text aren't taken for a level.
 //[Level] Message
 func SqrParser(m string) (Level, string) {
-	s := strings.Index(m, "[")
-	e := strings.Index(m, "]")
+	t := strings.TrimLeft(m, " ")
+	e := strings.Index(t, "]")
 
-	if s >= 0 && e >= 0 && e > s {
-		ls := m[s+1 : e]
-		r := m[e+1:]
+	if strings.HasPrefix(t, "[") && e > 0 {
+		ls := t[1:e]
+		r := t[e+1:]
 		r = strings.TrimLeft(r, " ")
 
 		l := StringToLevel(ls)
@@ -163,23 +169,74 @@ func SqrParser(m string) (Level, string) {
 	return Undefined, m
 }
 
-//New creates a new Logger
+//New creates a new Logger with its own Filter, which writes out everything
+//until rules are added with Include, Exclude or Default.
 func New(o io.Writer, p string, f int) *Logger {
-	l := &Logger{
+	fl := NewFilter()
+
+	l := &Logger{}
+	l.cfg.Store(&config{
 		output:    o,
 		prefix:    p,
 		flag:      f,
-		filter:    StdFilter,
+		filters:   fl,
+		filter:    fl.Match,
 		formatter: StdFormat,
 		parsers:   []Parser{StdParser},
-	}
+		summary:   DefaultSummaryInterval,
+	})
+	l.summarised = time.Now().UnixNano()
 
 	return l
 }
 
+//config is an immutable snapshot of a loggers settings. Setters copy the
+//current snapshot, change the copy and then swap it in so Write never sees a
+//partially applied change.
+type config struct {
+	flag   int
+	output io.Writer
+	prefix string
+
+	filters   *Filter
+	filter    func(*LogLine) bool
+	formatter Format
+	parsers   []Parser
+
+	structuredParsers []StructuredParser
+	fieldParsers      []FieldParser
+
+	explain int // write an explanation for 1 in explain lines.
+
+	summary time.Duration // how often lines suppressed by rate limits are reported.
+	dedup   time.Duration // the window repeated lines are collapsed in, see SetDedup.
+
+	recordAge time.Duration // the oldest line written by the flight recorder.
+	escalate  time.Duration // how long a package is escalated after an Error.
+}
+
+//config returns the current configuration snapshot of the logger.
+func (l *Logger) config() *config {
+	c, _ := l.cfg.Load().(*config)
+	if c == nil {
+		return &config{}
+	}
+	return c
+}
+
+//update applies fn to a copy of the current configuration and publishes it.
+func (l *Logger) update(fn func(*config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := *l.config()
+	fn(&c)
+	l.cfg.Store(&c)
+}
+
 //SetFilterFunc set the filter function on the logger.
 func (l *Logger) SetFilterFunc(f func(*LogLine) bool) {
-	l.filter = f
+	l.update(func(c *config) { c.filter = f })
 }
 
 //SetFilterFunc Set the Filter Function on the default logger.
@@ -189,7 +246,7 @@ func SetFilterFunc(f func(*LogLine) bool) {
 
 //FilterFunc returns the Current Filter Function
 func (l *Logger) FilterFunc() func(*LogLine) bool {
-	return l.filter
+	return l.config().filter
 }
 
 //FilterFunc returns the standard filter function
@@ -197,6 +254,42 @@ func FilterFunc() func(*LogLine) bool {
 	return std.FilterFunc()
 }
 
+//Filters returns the filter rules owned by the logger.
+func (l *Logger) Filters() *Filter {
+	return l.config().filters
+}
+
+//SetFilters replaces the filter rules of the logger and sets the filter
+//function to use them.
+func (l *Logger) SetFilters(f *Filter) {
+	l.update(func(c *config) {
+		c.filters = f
+		c.filter = f.Match
+	})
+}
+
+//Include adds filter object(s) to the filter of the logger and returns them to
+//allow you to set the required level.
+func (l *Logger) Include(packagenames ...string) filters {
+	return l.Filters().Include(packagenames...)
+}
+
+//Exclude adds filter object(s) to the filter of the logger and returns them to
+//allow you to set the required level.
+func (l *Logger) Exclude(packagenames ...string) filters {
+	return l.Filters().Exclude(packagenames...)
+}
+
+//Default sets the logging level the is output by all packages for the logger.
+func (l *Logger) Default(lvl Level) {
+	l.Filters().Default(lvl)
+}
+
+//Reset resets the filter rules of the logger.
+func (l *Logger) Reset() {
+	l.Filters().Reset()
+}
+
 //Flags returns the output flags of the std logger.
 func Flags() int {
 	return std.Flags()
@@ -204,7 +297,7 @@ func Flags() int {
 
 //Flags returns the output flags of the logger
 func (l *Logger) Flags() int {
-	return l.flag
+	return l.config().flag
 }
 
 //SetFlags sets the output flags on the default logger.
@@ -215,7 +308,7 @@ func SetFlags(f int) {
 
 //SetFlags sets the default flag on the logger.
 func (l *Logger) SetFlags(f int) {
-	l.flag = f
+	l.update(func(c *config) { c.flag = f })
 }
 
 //Output returns the output io.writer of the std logger.
@@ -225,7 +318,7 @@ func Output() io.Writer {
 
 //Output returns the output io.writer of the logger.
 func (l *Logger) Output() io.Writer {
-	return l.output
+	return l.config().output
 }
 
 //SetOutput sets the output io.writer of the std logger.
@@ -235,7 +328,7 @@ func SetOutput(o io.Writer) {
 
 //SetOutput sets the output io.writer of the logger.
 func (l *Logger) SetOutput(o io.Writer) {
-	l.output = o
+	l.update(func(c *config) { c.output = o })
 }
 
 //Formatter returns the formatter function of the std logger.
@@ -245,7 +338,7 @@ func Formatter() Format {
 
 //Formatter returns the formatter function of the logger.
 func (l *Logger) Formatter() Format {
-	return l.formatter
+	return l.config().formatter
 }
 
 //SetFormatter sets the output formatter of the std logger.
@@ -255,7 +348,7 @@ func SetFormatter(f Format) {
 
 //SetFormatter sets the output formatter of the logger.
 func (l *Logger) SetFormatter(f Format) {
-	l.formatter = f
+	l.update(func(c *config) { c.formatter = f })
 }
 
 //Prefix returns the prefix of the std logger.
@@ -265,7 +358,7 @@ func Prefix() string {
 
 //Prefix returns the prefix of the logger.
 func (l *Logger) Prefix() string {
-	return l.prefix
+	return l.config().prefix
 }
 
 //SetPrefix sets the prefix of the std logger.
@@ -275,7 +368,7 @@ func SetPrefix(p string) {
 
 //SetPrefix sets the prefix of the logger.
 func (l *Logger) SetPrefix(p string) {
-	l.prefix = p
+	l.update(func(c *config) { c.prefix = p })
 }
 
 //Parsers returns the current parsers in use by the std logger.
@@ -283,9 +376,9 @@ func Parsers() []Parser {
 	return std.Parsers()
 }
 
-//Parsers returns the current parsers in use by the logger.
+//Parsers returns a copy of the current parsers in use by the logger.
 func (l *Logger) Parsers() []Parser {
-	return l.parsers
+	return append([]Parser(nil), l.config().parsers...)
 }
 
 //SetParsers sets the parsers used by the std logger.
@@ -293,37 +386,89 @@ func SetParsers(p []Parser) {
 	std.SetParsers(p)
 }
 
-//SetParsers sets the parsers used by the logger.
+//SetParsers sets the parsers used by the logger. The slice is copied so later
+//changes by the caller don't affect the logger.
 func (l *Logger) SetParsers(p []Parser) {
-	l.parsers = p
+	p = append([]Parser(nil), p...)
+	l.update(func(c *config) { c.parsers = p })
 }
 
 // Logger used to capture logging output prior to filtering/output.
+// A Logger is safe for concurrent use, configuration can be changed while
+// other goroutines are writing to it.
 type Logger struct {
-	flag   int
-	output io.Writer
-	prefix string
+	explained    uint64 // count of lines sampled for explanation.
+	summarised   int64  // when suppressed lines were last reported, in unix nanoseconds.
+	summaryTimer int32  // 1 while a timer to write the summary is pending.
 
-	filter    func(*LogLine) bool
-	formatter Format
-	parsers   []Parser
+	mu  sync.Mutex   // serialises configuration changes.
+	cfg atomic.Value // *config, the current configuration snapshot.
+
+	//amu is held for writing by ApplyConfig while it changes the logger and
+	//its filter, and for reading while a line is written, so a line sees all
+	//of a config or none of it.
+	amu sync.RWMutex
+
+	wmu   sync.Mutex  // serialises writes to the output.
+	file  *os.File    // output file opened by ApplyConfig, changed holding amu and wmu.
+	dedup dedup       // run of repeated lines, guarded by wmu.
+	rec   recorder    // lines recently filtered out, see SetFlightRecorder.
+	esc   escalations // packages escalated after an Error, see SetEscalation.
 }
 
 // LogLine struct representing the parsed log message.
+// Package and Function are the import path and name of the function which
+// logged the line (i.e. github.com/acme/db and (*Pool).Acquire), resolved from
+// the call stack by Logger.Write.
 type LogLine struct {
 	Timestamp time.Time
 	File      string
 	Line      int
 	Message   string
 
+	Package  string
+	Function string
+
+	//Fields are the structured fields found in the message by the field
+	//parsers (see SetFieldParsers).
+	Fields Fields
+
 	Level Level
+
+	//thinned is set when a sample or rate limit drops the line rather than the
+	//rules, so the flight recorder doesn't keep it.
+	thinned bool
 }
 
 // Write is the implement the io.Writer to capture the message being written to log.
 func (l *Logger) Write(p []byte) (int, error) {
+	l.amu.RLock()
+	defer l.amu.RUnlock()
+
+	c := l.config()
 	log := StringToLogLine(string(p))
 
-	for _, p := range l.parsers {
+	f := caller()
+	log.Package, log.Function = splitFuncName(f.Function)
+	if log.File == "" {
+		//The log flags didn't include the file so use the callers.
+		log.File, log.Line = f.File, f.Line
+	}
+
+	for _, p := range c.structuredParsers {
+		lvl, msg, fields := p(log.Message)
+		if lvl != Undefined {
+			log.Level = lvl
+			log.Message = msg
+			log.Fields = fields
+			break
+		}
+	}
+
+	for _, p := range c.parsers {
+		if log.Level != Undefined {
+			break
+		}
 		lvl, msg := p(log.Message)
 		if lvl != Undefined {
 			log.Level = lvl
@@ -332,14 +477,61 @@ func (l *Logger) Write(p []byte) (int, error) {
 		}
 	}
 
-	if l.filter == nil || l.filter(&log) {
-		p = l.formatter(l.prefix, &log, l.flag)
-		return l.output.Write([]byte(p))
+	for _, fp := range c.fieldParsers {
+		log.Fields = append(log.Fields, fp(log.Message)...)
 	}
 
+	l.explain(c, &log)
+	l.summarise(c)
+	l.escalate(c, &log)
+
+	writeout, filtered := callSites.allow(&log, c.filter)
+	if writeout {
+		p = c.formatter(c.prefix, &log, c.flag)
+
+		l.wmu.Lock()
+		defer l.wmu.Unlock()
+		if l.duplicate(c, &log) {
+			return 0, io.EOF
+		}
+		l.dumpRecorder(c, &log)
+		return c.output.Write(p)
+	}
+
+	if filtered && !log.thinned {
+		l.rec.record(&log)
+	}
 	return 0, io.EOF
 }
 
+//notice writes a line about the logger itself at the level. It bypasses the
+//filter so it is reported even when lines of the level are filtered out.
+func (l *Logger) notice(lvl Level, format string, v ...interface{}) {
+	l.amu.RLock()
+	defer l.amu.RUnlock()
+
+	l.noticeTo(l.config(), lvl, format, v...)
+}
+
+//noticeTo implements notice for the configuration, it is used within Write
+//which already holds the config lock.
+func (l *Logger) noticeTo(c *config, lvl Level, format string, v ...interface{}) {
+	ll := LogLine{
+		Timestamp: time.Now(),
+		Level:     lvl,
+		Message:   fmt.Sprintf(format, v...) + "\n",
+	}
+	if c.flag&log.LUTC != 0 {
+		ll.Timestamp = ll.Timestamp.UTC()
+	}
+
+	b := c.formatter(c.prefix, &ll, c.flag)
+
+	l.wmu.Lock()
+	defer l.wmu.Unlock()
+	c.output.Write(b)
+}
+
 // Cheap integer to fixed-width decimal ASCII.  Give a negative width to avoid zero-padding.
 func itoa(buf *[]byte, i int, wid int) {
 	// Assemble decimal in reverse order.
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"log"
 	"os"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -332,3 +334,79 @@ func TestFilterIncludeInclude(t *testing.T) {
 	//Reset the filter for the next test.
 	logfilter.StdFilterReset()
 }
+
+func TestConcurrentConfiguration(t *testing.T) {
+	var b bytes.Buffer
+	l := logfilter.New(&b, "", 0)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				lg.Println("Debug: Message")
+			}
+			done <- true
+		}()
+	}
+
+	//Each configuration either writes out the Debug lines whole or drops them.
+	for j := 0; j < 100; j++ {
+		l.SetPrefix("")
+		l.SetParsers([]logfilter.Parser{logfilter.StdParser})
+		l.SetFormatter(logfilter.StdFormat)
+		l.Include("github.com/d2g").When(logfilter.Debug)
+		l.Exclude("github.com/d2g").When(logfilter.Trace)
+		l.Default(logfilter.Warning)
+		l.Default(logfilter.Undefined)
+	}
+
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	//The final configuration writes out the Debug lines.
+	lg.Println("Debug: Message")
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) < 1 || len(lines) > 401 {
+		t.Fatalf("Expected between %d and %d lines, actual %d", 1, 401, len(lines))
+	}
+	for _, ln := range lines {
+		if ln != "Debug: Message" {
+			t.Fatalf("Line Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Debug: Message", ln)
+		}
+	}
+
+	if s := l.Filters().String(); s != "-github.com/d2g=trace" {
+		t.Errorf("Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "-github.com/d2g=trace", s)
+	}
+}
+
+func TestWriteCaller(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetFilterFunc(func(ll *logfilter.LogLine) bool {
+		if ll.Package != "github.com/d2g/logfilter_test" || ll.Function != "TestWriteCaller" {
+			t.Errorf("Caller expected %s.%s, actual %s.%s", "github.com/d2g/logfilter_test", "TestWriteCaller", ll.Package, ll.Function)
+		}
+		return true
+	})
+
+	log.New(l, "", log.LstdFlags|log.Llongfile).Println("Debug: Message")
+}
+
+func TestCallerAllocs(t *testing.T) {
+	var f runtime.Frame
+	allocs := testing.AllocsPerRun(100, func() {
+		f = logfilter.Caller()
+	})
+
+	if allocs != 0 {
+		t.Errorf("Caller expected %d allocations, actual %.0f", 0, allocs)
+	}
+	if !strings.HasPrefix(f.Function, "github.com/d2g/logfilter_test.TestCallerAllocs") {
+		t.Errorf("Caller expected %s, actual %s", "github.com/d2g/logfilter_test.TestCallerAllocs", f.Function)
+	}
+}
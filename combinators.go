@@ -0,0 +1,122 @@
+package logfilter
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+//And returns a filter function which writes out a line when all of the filter
+//functions do. They are evaluated in order and evaluation stops at the first
+//which returns false.
+//i.e. SetFilterFunc(And(StdFilter, Not(MessageContains("connection reset"))))
+func And(fs ...func(*LogLine) bool) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		for _, f := range fs {
+			if !f(l) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+//Or returns a filter function which writes out a line when any of the filter
+//functions do. They are evaluated in order and evaluation stops at the first
+//which returns true.
+func Or(fs ...func(*LogLine) bool) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		for _, f := range fs {
+			if f(l) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//Not returns a filter function which writes out a line when f doesn't.
+func Not(f func(*LogLine) bool) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		return !f(l)
+	}
+}
+
+//LevelAtLeast returns a filter function which writes out lines of the level
+//and above.
+func LevelAtLeast(lvl Level) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		return rank(l.Level) >= rank(lvl)
+	}
+}
+
+//LevelAtMost returns a filter function which writes out lines of the level
+//and below.
+func LevelAtMost(lvl Level) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		return rank(l.Level) <= rank(lvl)
+	}
+}
+
+//FileMatches returns a filter function which writes out lines logged from the
+//package pattern, using the same matching as Include and Exclude.
+func FileMatches(find string) func(*LogLine) bool {
+	p := compilePattern(find)
+	return func(l *LogLine) bool {
+		return p.match(splitFile(l.File), splitSymbol(l))
+	}
+}
+
+//MessageContains returns a filter function which writes out lines whose
+//message contains s.
+func MessageContains(s string) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		return strings.Contains(l.Message, s)
+	}
+}
+
+//MessageMatches returns a filter function which writes out lines whose
+//message matches the regular expression.
+func MessageMatches(re *regexp.Regexp) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		return re.MatchString(l.Message)
+	}
+}
+
+//Sample returns a filter function which writes out 1 in n of the lines it is
+//called with, the first line is always written.
+func Sample(n int) func(*LogLine) bool {
+	var c uint64
+	return func(l *LogLine) bool {
+		if n <= 1 {
+			return true
+		}
+		return (atomic.AddUint64(&c, 1)-1)%uint64(n) == 0
+	}
+}
+
+//HasField returns a filter function which writes out lines with the field.
+func HasField(key string) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		_, ok := l.Fields.Get(key)
+		return ok
+	}
+}
+
+//FieldEquals returns a filter function which writes out lines where the field
+//has the value.
+func FieldEquals(key, value string) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		v, ok := l.Fields.Get(key)
+		return ok && v == value
+	}
+}
+
+//FieldMatches returns a filter function which writes out lines where the
+//field matches the regular expression.
+func FieldMatches(key string, re *regexp.Regexp) func(*LogLine) bool {
+	return func(l *LogLine) bool {
+		v, ok := l.Fields.Get(key)
+		return ok && re.MatchString(v)
+	}
+}
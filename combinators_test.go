@@ -0,0 +1,59 @@
+package logfilter_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestCombinators(t *testing.T) {
+	f := logfilter.Or(
+		logfilter.LevelAtLeast(logfilter.Error),
+		logfilter.And(
+			logfilter.FileMatches("acme/db"),
+			logfilter.MessageContains("slow query"),
+			logfilter.Not(logfilter.MessageMatches(regexp.MustCompile(`took=[0-9]ms`))),
+		),
+	)
+
+	tests := []struct {
+		file    string
+		level   logfilter.Level
+		message string
+		out     bool
+	}{
+		{"/src/acme/web/web.go", logfilter.Error, "failed", true},
+		{"/src/acme/web/web.go", logfilter.Debug, "slow query took=100ms", false},
+		{"/src/acme/db/db.go", logfilter.Debug, "slow query took=100ms", true},
+		{"/src/acme/db/db.go", logfilter.Debug, "slow query took=1ms", false},
+		{"/src/acme/db/db.go", logfilter.Debug, "fast query", false},
+	}
+
+	for _, test := range tests {
+		l := logfilter.LogLine{File: test.file, Level: test.level, Message: test.message}
+		if f(&l) != test.out {
+			t.Errorf("Message %q from %s expected %t, actual %t", test.message, test.file, test.out, !test.out)
+		}
+	}
+
+	l := logfilter.LogLine{Level: logfilter.Warning}
+	if !logfilter.LevelAtMost(logfilter.Warning)(&l) || logfilter.LevelAtMost(logfilter.Info)(&l) {
+		t.Errorf("LevelAtMost mismatch for %s", logfilter.LevelToString(l.Level))
+	}
+}
+
+func TestSample(t *testing.T) {
+	s := logfilter.Sample(3)
+
+	n := 0
+	for i := 0; i < 9; i++ {
+		if s(&logfilter.LogLine{}) {
+			n++
+		}
+	}
+
+	if n != 3 {
+		t.Errorf("Sample expected %d lines, actual %d", 3, n)
+	}
+}
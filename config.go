@@ -0,0 +1,395 @@
+package logfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	namesMu sync.RWMutex
+
+	//parserNames are the parsers which can be named in a config.
+	parserNames = map[string]Parser{
+		"std":    StdParser,
+		"sqr":    SqrParser,
+		"glog":   GlogParser,
+		"klog":   GlogParser,
+		"syslog": SyslogParser,
+	}
+
+	//structuredParserNames are the structured parsers which can be named in
+	//the parsers of a config.
+	structuredParserNames = map[string]StructuredParser{
+		"json":   JSONParser,
+		"logrus": LogrusParser,
+	}
+
+	//formatNames are the formatters which can be named in a config.
+	formatNames = map[string]Format{
+		"std":    StdFormat,
+		"sqr":    SqrFormat,
+		"logfmt": LogfmtFormat,
+	}
+
+	//fieldParserNames are the field parsers which can be named in a config.
+	fieldParserNames = map[string]FieldParser{
+		"logfmt": LogfmtFields,
+	}
+)
+
+//RegisterParser makes the parser available to configs by name.
+func RegisterParser(name string, p Parser) {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+	parserNames[strings.ToLower(name)] = p
+}
+
+//RegisterStructuredParser makes the structured parser available to the parsers
+//of configs by name.
+func RegisterStructuredParser(name string, p StructuredParser) {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+	structuredParserNames[strings.ToLower(name)] = p
+}
+
+//RegisterFieldParser makes the field parser available to configs by name.
+func RegisterFieldParser(name string, p FieldParser) {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+	fieldParserNames[strings.ToLower(name)] = p
+}
+
+//RegisterFormat makes the formatter available to configs by name.
+func RegisterFormat(name string, f Format) {
+	namesMu.Lock()
+	defer namesMu.Unlock()
+	formatNames[strings.ToLower(name)] = f
+}
+
+//flagNames are the log flags which can be named in a config.
+var flagNames = map[string]int{
+	"date":         log.Ldate,
+	"time":         log.Ltime,
+	"microseconds": log.Lmicroseconds,
+	"longfile":     log.Llongfile,
+	"shortfile":    log.Lshortfile,
+	"utc":          log.LUTC,
+	"stdflags":     log.LstdFlags,
+}
+
+//fileConfig is the JSON representation of the setup of a logger.
+type fileConfig struct {
+	Default   string            `json:"default"`
+	Rules     []adminRule       `json:"rules"`
+	Expr      string            `json:"expr"`
+	Sample    map[string]string `json:"sample"`
+	Parsers   []string          `json:"parsers"`
+	Fields    []string          `json:"fields"`
+	Formatter string            `json:"formatter"`
+	Prefix    string            `json:"prefix"`
+	Flags     []string          `json:"flags"`
+	Output    string            `json:"output"`
+}
+
+//LoadConfig applies the config file to the standard logger.
+//See (*Logger).ApplyConfig for the format.
+func LoadConfig(path string) error {
+	return std.LoadConfig(path)
+}
+
+//WatchConfig applies the config file to the standard logger and reapplies it
+//when it changes. See (*Logger).WatchConfig.
+func WatchConfig(path string, interval time.Duration) (func(), error) {
+	return std.WatchConfig(path, interval)
+}
+
+//LoadConfig reads the config file and applies it to the logger.
+func (l *Logger) LoadConfig(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return l.ApplyConfig(b)
+}
+
+//WatchConfig applies the config file to the logger and then polls it at the
+//interval, applying it again whenever it changes. An invalid change is logged
+//as a warning and ignored, leaving the running configuration in place. The
+//interval must be positive. The returned func stops watching.
+func (l *Logger) WatchConfig(path string, interval time.Duration) (func(), error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("logfilter: invalid config poll interval %v", interval)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.ApplyConfig(b); err != nil {
+		return nil, err
+	}
+
+	stop := make(chan bool)
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+			}
+
+			n, err := ioutil.ReadFile(path)
+			if err != nil || bytes.Equal(n, b) {
+				continue
+			}
+			b = n
+
+			if err := l.ApplyConfig(b); err != nil {
+				log.Printf("Warning: ignoring config %s: %v", path, err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }, nil
+}
+
+//ApplyConfig applies the JSON config to the logger. Fields which are missing
+//are left at their defaults:
+//	{
+//		"default": "warning",
+//		"rules": [
+//			{"package": "github.com/acme/db", "inclusive": true, "level": "debug"},
+//			{"package": "", "contains": "connection reset", "inclusive": false, "level": "off"}
+//		],
+//		"sample": {"trace": "1/100", "debug": "10%:callsite"},
+//		"expr": "",
+//		"parsers": ["sqr", "std"],
+//		"fields": ["logfmt"],
+//		"formatter": "sqr",
+//		"prefix": "",
+//		"flags": ["date", "time", "shortfile"],
+//		"output": "stderr"
+//	}
+//
+//The rules are as sent to the Handler, sample maps levels to the proportion
+//of their lines kept (see SampleLevel), parsers, fields and formatter are
+//named (see RegisterParser, RegisterFieldParser and RegisterFormat) and output
+//is stderr, stdout or the path of a file to append to, when it is omitted the
+//output is left as it is (i.e. as set by SetOutput). The whole config is
+//checked before any of it is applied, if it is invalid an error is returned
+//and the logger is unchanged.
+//
+//The rules replace those of the logger's Filter. A filter function set with
+//SetFilterFunc is kept, so it only sees the new rules if it uses the Filter
+//(i.e. And(l.Filters().Match, ...)). Rules in a config are permanent, ttl
+//isn't supported.
+func (l *Logger) ApplyConfig(data []byte) error {
+	fc := fileConfig{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&fc); err != nil {
+		return fmt.Errorf("logfilter: invalid config: %v", err)
+	}
+
+	c, err := fc.build()
+	if err != nil {
+		return err
+	}
+
+	//The expression is compiled against the filter now so it can be rejected.
+	f := l.Filters()
+	if f == nil {
+		f = NewFilter()
+	}
+	if fc.Expr != "" {
+		if _, err := f.CompileExpr(fc.Expr); err != nil {
+			return err
+		}
+	}
+
+	//Writes are held off while the filter and logger are changed so none sees
+	//part of the config. The output is opened while they are, so of two configs
+	//applied at once neither can replace the file the other opened unclosed.
+	l.amu.Lock()
+	defer l.amu.Unlock()
+
+	out, closer, err := l.configOutput(fc.Output)
+	if err != nil {
+		return err
+	}
+
+	f.update(func(rules) rules {
+		return c.rules
+	})
+	f.setLevelSamplers(c.samplers)
+	f.SetExpr(fc.Expr)
+
+	l.update(func(cfg *config) {
+		if cfg.filters == nil {
+			cfg.filter = f.Match
+		}
+		cfg.filters = f
+		cfg.parsers = c.parsers
+		cfg.structuredParsers = c.structured
+		cfg.fieldParsers = c.fields
+		cfg.formatter = c.formatter
+		cfg.prefix = fc.Prefix
+		cfg.flag = c.flag
+		if out != nil {
+			cfg.output = out
+		}
+	})
+
+	//Close a file opened by a previous config, no Write can still be using it
+	//as they are held off until the new config is in place.
+	l.wmu.Lock()
+	defer l.wmu.Unlock()
+	if l.file != nil && l.file != closer {
+		l.file.Close()
+	}
+	l.file = closer
+	return nil
+}
+
+//builtConfig holds the values of a config ready to apply.
+type builtConfig struct {
+	rules      rules
+	samplers   levelSamplers
+	parsers    []Parser
+	structured []StructuredParser
+	fields     []FieldParser
+	formatter  Format
+	flag       int
+}
+
+//build checks the config and converts it to the values to apply.
+func (fc fileConfig) build() (builtConfig, error) {
+	c := builtConfig{
+		rules:     defaultRules(),
+		parsers:   []Parser{StdParser},
+		formatter: StdFormat,
+		flag:      log.LstdFlags,
+	}
+
+	if fc.Default != "" {
+		lvl, err := specLevel(fc.Default)
+		if err != nil {
+			return c, fmt.Errorf("logfilter: invalid config: default: %v", err)
+		}
+		c.rules[0].when(lvl)
+	}
+
+	for _, a := range fc.Rules {
+		if a.TTL != "" || a.Expires != "" {
+			return c, fmt.Errorf("logfilter: invalid config: rule %q: ttl isn't supported in a config", a.Package)
+		}
+
+		f, err := a.filter()
+		if err != nil {
+			return c, err
+		}
+		if err := f.setLevel(a.Level); err != nil {
+			return c, fmt.Errorf("logfilter: invalid config: rule %q: %v", a.Package, err)
+		}
+		c.rules = c.rules.set(f)
+	}
+
+	c.samplers = levelSamplers{}
+	for n, v := range fc.Sample {
+		lvl, err := specLevel(n)
+		if err != nil {
+			return c, fmt.Errorf("logfilter: invalid config: sample: %v", err)
+		}
+		s, err := parseSample(v)
+		if err != nil {
+			return c, fmt.Errorf("logfilter: invalid config: sample: %v", err)
+		}
+		if s != nil {
+			c.samplers[lvl] = s
+		}
+	}
+
+	namesMu.RLock()
+	defer namesMu.RUnlock()
+
+	if fc.Parsers != nil {
+		c.parsers = []Parser{}
+		for _, n := range fc.Parsers {
+			if p, ok := structuredParserNames[strings.ToLower(n)]; ok {
+				c.structured = append(c.structured, p)
+				continue
+			}
+			p, ok := parserNames[strings.ToLower(n)]
+			if !ok {
+				return c, fmt.Errorf("logfilter: invalid config: unknown parser %q", n)
+			}
+			c.parsers = append(c.parsers, p)
+		}
+	}
+
+	for _, n := range fc.Fields {
+		p, ok := fieldParserNames[strings.ToLower(n)]
+		if !ok {
+			return c, fmt.Errorf("logfilter: invalid config: unknown field parser %q", n)
+		}
+		c.fields = append(c.fields, p)
+	}
+
+	if fc.Formatter != "" {
+		f, ok := formatNames[strings.ToLower(fc.Formatter)]
+		if !ok {
+			return c, fmt.Errorf("logfilter: invalid config: unknown formatter %q", fc.Formatter)
+		}
+		c.formatter = f
+	}
+
+	if fc.Flags != nil {
+		c.flag = 0
+		for _, n := range fc.Flags {
+			f, ok := flagNames[strings.ToLower(n)]
+			if !ok {
+				return c, fmt.Errorf("logfilter: invalid config: unknown flag %q", n)
+			}
+			c.flag |= f
+		}
+	}
+
+	return c, nil
+}
+
+//configOutput returns the writer for the output named in a config, and the
+//file if one is opened. The file opened by the previous config is reused if it
+//is named again. No output is named by a config without one, which keeps the
+//file of the previous config open. The caller must hold the apply lock.
+func (l *Logger) configOutput(name string) (io.Writer, *os.File, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, l.file, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	case "stdout":
+		return os.Stdout, nil, nil
+	}
+
+	if l.file != nil && l.file.Name() == name {
+		return l.file, l.file, nil
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logfilter: invalid config: output: %v", err)
+	}
+	return f, f, nil
+}
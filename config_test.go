@@ -0,0 +1,262 @@
+package logfilter_test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestApplyConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.log")
+	l := logfilter.New(ioutil.Discard, "", 0)
+	err = l.ApplyConfig([]byte(`{
+		"default": "warning",
+		"rules": [{"package": "github.com/d2g/logfilter", "inclusive": true, "level": "debug"}],
+		"parsers": ["sqr", "std"],
+		"formatter": "sqr",
+		"prefix": "app: ",
+		"flags": [],
+		"output": "` + out + `"
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+	lg.Println("[TRACE] Message")
+	lg.Println("[DEBUG] Message")
+
+	if s := l.Filters().String(); s != "warning,github.com/d2g/logfilter=debug" {
+		t.Errorf("Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "warning,github.com/d2g/logfilter=debug", s)
+	}
+
+	//Invalid configs leave the logger unchanged.
+	for _, c := range []string{
+		`{"default": "loud"}`,
+		`{"parsers": ["xml"]}`,
+		`{"formatter": "xml"}`,
+		`{"flags": ["colour"]}`,
+		`{"rules": [{"package": "acme", "level": "debug..loud"}]}`,
+		`{"expr": "level >="}`,
+		`{"sample": {"trace": "1/0"}}`,
+		`{"sample": {"loud": "1/10"}}`,
+		`{"unknown": true}`,
+		`{"rules": [{"package": "acme", "inclusive": true, "level": "debug", "ttl": "1ms"}]}`,
+		`{`,
+	} {
+		if err := l.ApplyConfig([]byte(c)); err == nil {
+			t.Errorf("Config %s expected error", c)
+		}
+	}
+	lg.Println("[DEBUG] Message")
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "app: [Debug] Message\napp: [Debug] Message\n" {
+		t.Errorf("Output Mismatch Expected:\"%s\" Actual:\"%s\"\n", "app: [Debug] Message\napp: [Debug] Message\n", b)
+	}
+}
+
+func TestApplyConfigStructuredParsers(t *testing.T) {
+	l := logfilter.New(ioutil.Discard, "", 0)
+	if err := l.ApplyConfig([]byte(`{"parsers": ["json", "std", "logrus"]}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if n := len(l.StructuredParsers()); n != 2 {
+		t.Errorf("Structured Parsers Mismatch Expected:%d Actual:%d\n", 2, n)
+	}
+	if n := len(l.Parsers()); n != 1 {
+		t.Errorf("Parsers Mismatch Expected:%d Actual:%d\n", 1, n)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logfilter.json")
+	if err := ioutil.WriteFile(path, []byte(`{"default": "error"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	l := logfilter.New(&b, "", 0)
+	stop, err := l.WatchConfig(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer stop()
+
+	if s := l.Filters().String(); s != "error" {
+		t.Errorf("Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "error", s)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"default": "debug"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Filters().String() != "debug" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := l.Filters().String(); s != "debug" {
+		t.Errorf("Reloaded Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "debug", s)
+	}
+
+	if _, err := l.WatchConfig(path, 0); err == nil {
+		t.Errorf("Zero interval expected error")
+	}
+
+	if _, err := l.WatchConfig(filepath.Join(dir, "missing.json"), time.Second); err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("Missing config expected error, actual %v", err)
+	}
+}
+
+func TestApplyConfigConcurrentWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outs := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+
+	l := logfilter.New(ioutil.Discard, "", 0)
+	if err := l.ApplyConfig([]byte(`{"flags": [], "output": "` + outs[0] + `"}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	const writers, lines = 4, 200
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				lg.Printf("Info: %d %d", w, i)
+			}
+		}(w)
+	}
+
+	//Switching between files while lines are written mustn't lose any.
+	for i := 0; i < 50; i++ {
+		prefix := fmt.Sprintf("p%d: ", i)
+		if err := l.ApplyConfig([]byte(`{"flags": [], "prefix": "` + prefix + `", "output": "` + outs[i%2] + `"}`)); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	wg.Wait()
+	if err := l.ApplyConfig([]byte(`{}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	n := 0
+	for _, o := range outs {
+		f, err := os.Open(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			n++
+		}
+		f.Close()
+	}
+
+	if n != writers*lines {
+		t.Errorf("Expected %d lines written, actual %d", writers*lines, n)
+	}
+}
+
+func TestApplyConfigKeepsFilterFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out.log")
+	l := logfilter.New(ioutil.Discard, "", 0)
+	l.SetFilterFunc(logfilter.And(l.Filters().Match, logfilter.MessageContains("keep")))
+	if err := l.ApplyConfig([]byte(`{"default": "warning", "flags": [], "output": "` + out + `"}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+	lg.Println("Warning: drop")
+	lg.Println("Warning: keep")
+	lg.Println("Info: keep")
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Warning: keep\n" {
+		t.Errorf("Filter Func Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: keep\n", b)
+	}
+}
+
+func TestApplyConfigKeepsOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	//A config without an output leaves the output set by SetOutput.
+	var b bytes.Buffer
+	l := logfilter.New(ioutil.Discard, "", 0)
+	l.SetOutput(&b)
+	if err := l.ApplyConfig([]byte(`{"default": "warning", "flags": []}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+	lg.Println("Warning: Message")
+
+	if b.String() != "Warning: Message\n" {
+		t.Errorf("Output Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: Message\n", b.String())
+	}
+
+	//Or the file opened by a previous config, which is kept open.
+	out := filepath.Join(dir, "out.log")
+	if err := l.ApplyConfig([]byte(`{"flags": [], "output": "` + out + `"}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if err := l.ApplyConfig([]byte(`{"default": "error", "flags": []}`)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	lg.Println("Warning: Dropped")
+	lg.Println("Error: Message")
+
+	f, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(f) != "Error: Message\n" {
+		t.Errorf("File Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Error: Message\n", f)
+	}
+}
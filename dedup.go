@@ -0,0 +1,83 @@
+package logfilter
+
+import (
+	"fmt"
+	"time"
+)
+
+//dedup holds the run of identical lines a logger is collapsing. It is guarded
+//by the write lock of the logger.
+type dedup struct {
+	last  LogLine     // the line written at the start of the run.
+	count int         // the lines suppressed since.
+	run   int         // identifies the run so a stale timer is ignored.
+	timer *time.Timer // reports the run if it doesn't end within the window.
+}
+
+//SetDedup sets the window the standard logger collapses repeated lines in.
+func SetDedup(window time.Duration) {
+	std.SetDedup(window)
+}
+
+//SetDedup makes the logger collapse consecutive identical lines, the same
+//level and message from the same call site, syslogd style. The first line is
+//written and the repeats are counted, then when a different line is written or
+//the window passes a single "last message repeated N times" line is written in
+//their place. Zero turns it off.
+func (l *Logger) SetDedup(window time.Duration) {
+	l.update(func(c *config) { c.dedup = window })
+}
+
+//duplicate returns true if the line repeats the last line written and so is
+//counted rather than written, otherwise it ends any run and starts a new one.
+//The caller must hold the write lock.
+func (l *Logger) duplicate(c *config, ll *LogLine) bool {
+	d := &l.dedup
+	if c.dedup <= 0 {
+		d.end(l, c)
+		return false
+	}
+
+	if d.last.File == ll.File && d.last.Line == ll.Line && d.last.Level == ll.Level && d.last.Message == ll.Message {
+		d.count++
+		if d.count == 1 {
+			run := d.run
+			d.timer = time.AfterFunc(c.dedup, func() {
+				l.wmu.Lock()
+				defer l.wmu.Unlock()
+
+				if l.dedup.run == run {
+					l.dedup.report(l.config())
+				}
+			})
+		}
+		return true
+	}
+
+	d.end(l, c)
+	d.last = *ll
+	return false
+}
+
+//end reports the run if any lines were suppressed and forgets the last line.
+func (d *dedup) end(l *Logger, c *config) {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.report(c)
+	d.last = LogLine{}
+}
+
+//report writes the repeat line for the run if any lines were suppressed, the
+//next identical line starts a new count.
+func (d *dedup) report(c *config) {
+	if d.count > 0 {
+		r := d.last
+		r.Timestamp = time.Now()
+		r.Message = fmt.Sprintf("last message repeated %d times\n", d.count)
+		c.output.Write(c.formatter(c.prefix, &r, c.flag))
+	}
+	d.count = 0
+	d.run++
+}
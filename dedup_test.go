@@ -0,0 +1,76 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+//syncBuffer is a bytes.Buffer which can be read while the logger writes.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestDedup(t *testing.T) {
+	var b syncBuffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetDedup(time.Hour)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	for i := 0; i < 4; i++ {
+		lg.Println("Warning: Flapping")
+	}
+	lg.Println("Warning: Recovered")
+
+	expected := "Warning: Flapping\nWarning: last message repeated 3 times\nWarning: Recovered\n"
+	if b.String() != expected {
+		t.Errorf("Dedup Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	var b syncBuffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetDedup(20 * time.Millisecond)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	for i := 0; i < 3; i++ {
+		lg.Println("Error: Flapping")
+	}
+
+	//The run is reported when the window passes without it ending.
+	expected := "Error: Flapping\nError: last message repeated 2 times\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for b.String() != expected && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if b.String() != expected {
+		t.Errorf("Dedup Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+
+	//Lines from a different call site aren't repeats.
+	lg.Println("Error: Flapping")
+	if n := strings.Count(b.String(), "Error: Flapping\n"); n != 2 {
+		t.Errorf("Expected %d lines from different call sites, actual %d", 2, n)
+	}
+}
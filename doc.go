@@ -10,6 +10,11 @@ standard logging levels like:
 	Error
 	Fatal
 
+Further levels can be added between them with RegisterLevel, and alternative
+names (i.e. WARN or ERR) with SetLevelAlias. Single letters (i.e. E) and words
+(i.e. notice or panic) aren't aliases unless they are added with
+SetLevelAliases(ShortLevelAliases()) or SetLevelAliases(WordLevelAliases()).
+
 Unlike other packages log filter doesn't require the package to specifically
 import an additional package over the standard logging package. It does however
 require them to follow a convention.
@@ -60,6 +65,47 @@ messages:
 		//dummy.go:17: Info: This is a Info message
 	}
 
+Package names given to Include and Exclude are matched against whole segments
+of the path of the file the line was logged from, so "acme/db" matches the
+package acme/db and its subpackages but not acme/dbutil:
+
+	acme/db       the package acme/db and its subpackages.
+	acme/db/...   the same as acme/db.
+	acme/db$      only the package acme/db.
+	acme/db*      a glob, * matches within a single segment (i.e. acme/dbutil).
+	/src/acme/db  a leading / anchors the pattern to the start of the path.
+
+Patterns are also matched against the import path and function name of the
+code which logged the line, so rules work the same whatever the file layout
+(GOPATH, module cache, vendor or -trimpath) and can target a single function:
+
+	logfilter.Include("github.com/acme/db.(*Pool).Acquire").When(logfilter.Trace)
+
+When more than one rule matches a line the one with the most segments wins.
+
+A rule can also be rate limited, lines over the limit are dropped and a summary
+of how many is written periodically:
+
+	logfilter.Include("github.com/acme/db").When(logfilter.Warning).Limit(10, 20)
+
+The filter can also be described by a spec string, which is applied with
+ApplySpec or read from the LOGFILTER environment variable at startup so the
+verbosity of a deployed binary can be changed without a rebuild:
+
+	LOGFILTER="warning,github.com/acme/db=debug,-github.com/acme/noisy=fatal"
+
+Samples and rate limits follow a rule after a semicolon:
+
+	LOGFILTER="warning,github.com/acme/db=debug;sample=1/10;limit=10/s:20"
+
+The whole setup of a logger (rules, parsers, formatter, prefix, flags and
+output) can also be kept in a JSON file, applied with LoadConfig or with
+WatchConfig which reapplies it whenever the file is edited.
+
+HandleSignals lets the default level be changed without either, SIGUSR1 writes
+out more, SIGUSR2 less and SIGHUP reloads the configuration. Without a config
+file SIGHUP reapplies the LOGFILTER spec, discarding any rules changed since.
+
 If you've previously used logutils or a square based convention then look at the
 example included in example_logutils_test.go
 
@@ -0,0 +1,63 @@
+package logfilter
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+//SetEscalation sets how long the standard logger escalates a package for.
+func SetEscalation(d time.Duration) {
+	std.SetEscalation(d)
+}
+
+//SetEscalation makes the logger write out the Debug lines of a package for
+//the duration after it logs an Error or Fatal line, then revert the package to
+//its previous rule (see For). Packages already writing out all of their Debug
+//lines, not a sample or at a rate limit, aren't escalated. The escalation and
+//revert are written at Info. Zero turns it off.
+func (l *Logger) SetEscalation(d time.Duration) {
+	l.update(func(c *config) { c.escalate = d })
+}
+
+//escalations holds the packages a logger has escalated.
+type escalations struct {
+	active sync.Map // package pattern -> struct{}
+}
+
+//escalate escalates the package of an Error or Fatal line.
+func (l *Logger) escalate(c *config, ll *LogLine) {
+	if c.escalate <= 0 || c.filters == nil || (ll.Level != Error && ll.Level != Fatal) {
+		return
+	}
+
+	//The package is matched exactly by import path, or by the directory of
+	//the file if the caller is unknown.
+	pkg := ll.Package
+	if pkg == "" {
+		if ll.File == "" {
+			return
+		}
+		pkg = path.Dir(ll.File)
+	}
+	pkg += "$"
+
+	//Packages whose Debug lines are sampled or rate limited are escalated so
+	//all of them are written out.
+	probe := *ll
+	probe.Level = Debug
+	if e := c.filters.Explain(&probe); e.Written && len(e.Samples) == 0 && e.Limit == "" {
+		return
+	}
+
+	if _, ok := l.esc.active.LoadOrStore(pkg, struct{}{}); ok {
+		return
+	}
+
+	f := c.filters
+	f.Include(pkg).When(Debug).expireAfter(c.escalate, func() {
+		l.esc.active.Delete(pkg)
+		l.notice(Info, "logfilter: reverted escalation of %s", pkg)
+	})
+	l.noticeTo(c, Info, "logfilter: escalated %s to Debug for %s after %s", pkg, c.escalate, LevelToString(ll.Level))
+}
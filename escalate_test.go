@@ -0,0 +1,46 @@
+package logfilter_test
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestEscalation(t *testing.T) {
+	var b syncBuffer
+
+	l := logfilter.New(&b, "", 0)
+	l.Default(logfilter.Warning)
+	l.SetEscalation(50 * time.Millisecond)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println("Debug: Before")
+	lg.Println("Error: Failed")
+	lg.Println("Debug: During")
+
+	expected := "Info: logfilter: escalated github.com/d2g/logfilter_test$ to Debug for 50ms after Error\n" +
+		"Error: Failed\n" +
+		"Debug: During\n"
+	if b.String() != expected {
+		t.Errorf("Escalation Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+
+	//The package reverts once the escalation expires.
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(b.String(), "reverted") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expected += "Info: logfilter: reverted escalation of github.com/d2g/logfilter_test$\n"
+	lg.Println("Debug: After")
+	if b.String() != expected {
+		t.Errorf("Revert Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+
+	if s := l.Filters().String(); s != "warning" {
+		t.Errorf("Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "warning", s)
+	}
+}
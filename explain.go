@@ -0,0 +1,168 @@
+package logfilter
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+//Explanation describes why a line is or isn't written out. A line which is
+//written can still be dropped by the random samples or the rate limit it is
+//subject to, Explain reports them but doesn't draw from a sample or take a
+//token so it doesn't change what is written out.
+type Explanation struct {
+	Written bool
+	Reason  string
+
+	//Samples are the random samples (of the deciding rule and the level) a
+	//written line is subject to in the spec format, i.e. 1/100.
+	Samples []string
+
+	//Limit is the rate limit of the deciding rule a written line is subject
+	//to, i.e. 10/s:20, empty if there isn't one.
+	Limit string
+
+	//Rules are the filter rules evaluated in the order they are held, empty
+	//when the call site state decided.
+	Rules []RuleExplanation
+}
+
+//RuleExplanation describes how a single filter rule was evaluated for a line.
+type RuleExplanation struct {
+	Rule        string // the rule in the spec format.
+	Package     bool   // the package pattern matched the line.
+	Level       bool   // the levels of the rule include the line level.
+	Message     bool   // the message condition of the rule matched the line.
+	Specificity int    // the rule with the highest specificity wins.
+	Won         bool   // the rule decided if the line is written.
+}
+
+//String returns the explanation on a single line.
+func (e Explanation) String() string {
+	s := "dropped"
+	if e.Written {
+		s = "written"
+	}
+	s += ": " + e.Reason
+
+	var r []string
+	for _, re := range e.Rules {
+		w := ""
+		if re.Won {
+			w = " won"
+		}
+		r = append(r, fmt.Sprintf("%q(package:%t level:%t message:%t specificity:%d%s)", re.Rule, re.Package, re.Level, re.Message, re.Specificity, w))
+	}
+
+	var subject []string
+	for _, sm := range e.Samples {
+		subject = append(subject, "sample "+sm)
+	}
+	if e.Limit != "" {
+		subject = append(subject, "limit "+e.Limit)
+	}
+	if len(subject) > 0 {
+		s += "; subject to " + strings.Join(subject, " and ")
+	}
+
+	if len(r) > 0 {
+		s += "; rules: " + strings.Join(r, " ")
+	}
+	return s
+}
+
+//sampled records the sample a written line is subject to. A sample chosen by
+//call site or message always keeps or drops the same lines so it is decided,
+//a random sample is only reported.
+func (e *Explanation) sampled(s *sampler, l *LogLine) {
+	if s == nil || !e.Written {
+		return
+	}
+	if s.by == SampleRandom {
+		e.Samples = append(e.Samples, s.String())
+		return
+	}
+	if !s.keep(l) {
+		e.Written = false
+		e.Reason = fmt.Sprintf("%s, but the line isn't in the sample %s", e.Reason, s)
+	}
+}
+
+//Explain returns why the line is or isn't written out by the standard logger.
+func Explain(l *LogLine) Explanation {
+	return std.Explain(l)
+}
+
+//Explain returns why the line is or isn't written out by the logger, taking
+//into account the call site state and the rules of its Filter. A custom filter
+//function set with SetFilterFunc isn't explained.
+func (l *Logger) Explain(ll *LogLine) Explanation {
+	switch callSites.state(ll.File, ll.Line) {
+	case CallSiteEnabled:
+		return Explanation{Written: true, Reason: "call site is enabled"}
+	case CallSiteDisabled:
+		return Explanation{Written: false, Reason: "call site is disabled"}
+	}
+
+	f := l.Filters()
+	if f == nil {
+		return Explanation{Written: true, Reason: "logger has no filter"}
+	}
+	return f.Explain(ll)
+}
+
+//Explain returns why the line is or isn't written out by the filter rules,
+//the samples and rate limits it is subject to. The samples and limits of the
+//rules aren't reported when an expression decides.
+func (f *Filter) Explain(l *LogLine) Explanation {
+	e := Explanation{}
+	r := f.load()
+	_, i := r.evaluate(l, &e)
+
+	if x := f.expr(); x != nil {
+		e.Written = x.explain(l)
+		e.Reason = fmt.Sprintf("expression %q is %t (the rules alone: %s)", x.src, e.Written, e.Reason)
+	} else if e.Written {
+		e.sampled(r[i].sample, l)
+		if r[i].limit != nil && e.Written {
+			e.Limit = r[i].limit.String()
+		}
+	}
+
+	e.sampled(f.levelSamplers()[l.Level], l)
+	return e
+}
+
+//SetExplain sets the standard logger to write an explanation for 1 in n lines.
+func SetExplain(n int) {
+	std.SetExplain(n)
+}
+
+//SetExplain sets the logger to write an explanation (see Explain) before 1 in n
+//lines, whether they are written out or not. Zero turns explanations off.
+func (l *Logger) SetExplain(n int) {
+	l.update(func(c *config) { c.explain = n })
+}
+
+//explain writes an explanation of the line if it is sampled.
+func (l *Logger) explain(c *config, ll *LogLine) {
+	if c.explain <= 0 || atomic.AddUint64(&l.explained, 1)%uint64(c.explain) != 0 {
+		return
+	}
+
+	var b []byte
+	b = append(b, c.prefix...)
+	b = append(b, "Explain: "...)
+	b = append(b, ll.File...)
+	b = append(b, ':')
+	itoa(&b, ll.Line, -1)
+	b = append(b, ' ')
+	b = append(b, LevelToString(ll.Level)...)
+	b = append(b, ' ')
+	b = append(b, l.Explain(ll).String()...)
+	b = append(b, '\n')
+
+	l.wmu.Lock()
+	defer l.wmu.Unlock()
+	c.output.Write(b)
+}
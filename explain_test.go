@@ -0,0 +1,98 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestExplain(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Warning)
+	f.Include("acme/db").When(logfilter.Debug)
+	f.Exclude("acme/db/sql").When(logfilter.Debug)
+
+	l := logfilter.LogLine{File: "/src/acme/db/pool.go", Level: logfilter.Debug}
+	e := f.Explain(&l)
+
+	if !e.Written || len(e.Rules) != 3 {
+		t.Fatalf("Explain expected written with %d rules, actual %t with %d rules", 3, e.Written, len(e.Rules))
+	}
+
+	if !e.Rules[1].Won || e.Rules[0].Won || e.Rules[2].Won || e.Rules[2].Package || e.Rules[0].Level {
+		t.Errorf("Explain rules mismatch: %s", e)
+	}
+
+	if !strings.Contains(e.Reason, `"acme/db=debug"`) {
+		t.Errorf("Explain reason expected to name rule %q, actual %s", "acme/db=debug", e.Reason)
+	}
+
+	l.File = "/src/acme/web/web.go"
+	if e = f.Explain(&l); e.Written || e.Reason == "" {
+		t.Errorf("Explain expected dropped with a reason, actual %s", e)
+	}
+}
+
+func TestExplainSampleLimit(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Warning)
+	f.Include("acme/db").When(logfilter.Debug).Limit(1, 1).Sample(2, logfilter.SampleRandom)
+	f.Include("acme/web").When(logfilter.Debug).SamplePercent(0, logfilter.SampleCallSite)
+
+	l := logfilter.LogLine{File: "/src/acme/db/pool.go", Level: logfilter.Debug}
+	for i := 0; i < 3; i++ {
+		e := f.Explain(&l)
+		if !e.Written || len(e.Samples) != 1 || e.Samples[0] != "1/2" || e.Limit != "1/s:1" {
+			t.Fatalf("Explain expected written subject to the sample and limit, actual %s", e)
+		}
+	}
+
+	//Explain doesn't take the token, so the burst of one line is still there.
+	f.Remove("acme/db")
+	f.Include("acme/db").When(logfilter.Debug).Limit(1, 1)
+	f.Explain(&l)
+	if !f.Match(&l) || f.Match(&l) {
+		t.Errorf("Explain expected to leave the token for the first line only")
+	}
+
+	//Nor does the term rules of an expression.
+	f.Include("acme/db").When(logfilter.Debug).Limit(1, 1)
+	if err := f.SetExpr("rules"); err != nil {
+		t.Fatalf("Unexpected error setting expression: %v", err)
+	}
+	f.Explain(&l)
+	if !f.Match(&l) || f.Match(&l) {
+		t.Errorf("Explain of the expression expected to leave the token for the first line only")
+	}
+	f.SetExpr("")
+
+	l.File = "/src/acme/web/web.go"
+	if e := f.Explain(&l); e.Written || !strings.Contains(e.Reason, "sample 0%:callsite") {
+		t.Errorf("Explain expected dropped by the call site sample, actual %s", e)
+	}
+
+	f.Include("acme/web").When(logfilter.Debug)
+	f.SampleLevel(logfilter.Debug, 10, logfilter.SampleRandom)
+	if e := f.Explain(&l); !e.Written || len(e.Samples) != 1 || e.Samples[0] != "1/10" {
+		t.Errorf("Explain expected written subject to the level sample, actual %s", e)
+	}
+}
+
+func TestSetExplain(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.Default(logfilter.Warning)
+	l.SetExplain(2)
+
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+	lg.Println("Debug: Message")
+	lg.Println("Debug: Message")
+
+	if !strings.HasPrefix(b.String(), "Explain: ") || !strings.Contains(b.String(), "Debug dropped: ") || strings.Count(b.String(), "\n") != 1 {
+		t.Errorf("Expected one explanation, actual \"%s\"", b.String())
+	}
+}
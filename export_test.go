@@ -0,0 +1,21 @@
+package logfilter
+
+import "runtime"
+
+// ResetLevels removes the levels added with RegisterLevel and restores the
+// default aliases, so tests can register levels without affecting each other.
+func ResetLevels() {
+	aliasMu.Lock()
+	registry.Store(&levelRegistry{})
+	aliasMu.Unlock()
+
+	ResetLevelAliases()
+}
+
+// Caller returns the frame of the function calling it, as Write finds the
+// function logging a line.
+//
+//go:noinline
+func Caller() runtime.Frame {
+	return caller()
+}
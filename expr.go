@@ -0,0 +1,461 @@
+package logfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ExprError is returned when a filter expression can't be compiled. Pos is the
+//position (from 1) of the character in the expression the error was found at.
+type ExprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("logfilter: expression error at %d: %s", e.Pos, e.Msg)
+}
+
+//CompileExpr compiles a filter expression into a filter function, the term
+//rules refers to the standard filter. See (*Filter).CompileExpr.
+func CompileExpr(s string) (func(*LogLine) bool, error) {
+	return stdFilters.CompileExpr(s)
+}
+
+//SetExpr sets the expression deciding which lines are written out by the
+//standard filter. See (*Filter).SetExpr.
+func SetExpr(s string) error {
+	return stdFilters.SetExpr(s)
+}
+
+//CompileExpr compiles a filter expression into a filter function. The
+//expression combines comparisons of the LogLine fields with && (and), || (or),
+//! (not) and parentheses:
+//	level >= warning || (file ~ "acme/db" && msg contains "slow query")
+//
+//The fields and the operators they support are:
+//	level                     == != < <= > >=  against a level name.
+//	line                      == != < <= > >=  against a number.
+//	time                      == != < <= > >=  against a quoted time (RFC3339 or 2006/01/02 15:04:05).
+//	file package function msg == != contains matches ~  against a quoted string.
+//	field.<key>               == != contains matches ~  against a quoted string.
+//
+//contains tests for a substring and matches for a regular expression. ~ matches
+//file against a package pattern as Include does, and the other fields against
+//a regular expression. A field.<key> which the line doesn't have is "". The
+//term rules is true when the rules of the filter write out the line.
+func (f *Filter) CompileExpr(s string) (func(*LogLine) bool, error) {
+	return f.compileExpr(s, false)
+}
+
+//compileExpr implements CompileExpr. When explain is set the term rules only
+//evaluates the rules, without drawing from their samples or taking a token
+//from their rate limits, so Explain doesn't change what is written out.
+func (f *Filter) compileExpr(s string, explain bool) (func(*LogLine) bool, error) {
+	p := &exprParser{filter: f, explain: explain}
+	if err := p.lex(s); err != nil {
+		return nil, err
+	}
+
+	fn, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return fn, nil
+}
+
+//SetExpr sets the expression deciding which lines are written out by the
+//filter (see CompileExpr), use the term rules to include the decision of the
+//filter rules. An empty expression removes it leaving the rules to decide. If
+//the expression is invalid an error is returned and the filter is unchanged.
+func (f *Filter) SetExpr(s string) error {
+	x := &filterExpr{}
+
+	if strings.TrimSpace(s) != "" {
+		fn, err := f.CompileExpr(s)
+		if err != nil {
+			return err
+		}
+		x.src, x.fn = s, fn
+
+		//The expression compiled, so the second compile can't fail.
+		x.explain, _ = f.compileExpr(s, true)
+	}
+
+	f.x.Store(x)
+	return nil
+}
+
+//Expr returns the expression set on the filter, or "" if there isn't one.
+func (f *Filter) Expr() string {
+	if x := f.expr(); x != nil {
+		return x.src
+	}
+	return ""
+}
+
+//filterExpr is a compiled expression set on a Filter.
+type filterExpr struct {
+	src     string
+	fn      func(*LogLine) bool
+	explain func(*LogLine) bool // fn for Explain, see compileExpr.
+}
+
+//expr returns the expression set on the filter or nil.
+func (f *Filter) expr() *filterExpr {
+	x, _ := f.x.Load().(*filterExpr)
+	if x == nil || x.fn == nil {
+		return nil
+	}
+	return x
+}
+
+//tokenKind is the type of a token in an expression.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+//token is a single token in an expression.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+//exprParser is a recursive descent parser for filter expressions, which
+//compiles them into filter functions as they are parsed.
+type exprParser struct {
+	filter  *Filter
+	explain bool // compile the term rules for Explain.
+	tokens  []token
+	next    int
+}
+
+func (p *exprParser) errorf(t token, format string, args ...interface{}) error {
+	return &ExprError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+//lex splits the expression into tokens.
+func (p *exprParser) lex(s string) error {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z' || s[j] >= '0' && s[j] <= '9') {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokIdent, s[i:j], i + 1})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			p.tokens = append(p.tokens, token{tokNumber, s[i:j], i + 1})
+			i = j
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return &ExprError{Pos: i + 1, Msg: "unterminated string"}
+			}
+			v, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return &ExprError{Pos: i + 1, Msg: "invalid string"}
+			}
+			p.tokens = append(p.tokens, token{tokString, v, i + 1})
+			i = j + 1
+		default:
+			op := ""
+			for _, o := range []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "~", "(", ")"} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return &ExprError{Pos: i + 1, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			p.tokens = append(p.tokens, token{tokOp, op, i + 1})
+			i += len(op)
+		}
+	}
+
+	p.tokens = append(p.tokens, token{tokEOF, "", len(s) + 1})
+	return nil
+}
+
+//peek returns the next token without consuming it.
+func (p *exprParser) peek() token {
+	return p.tokens[p.next]
+}
+
+//take consumes and returns the next token.
+func (p *exprParser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+//or parses: and ("||" and)*
+func (p *exprParser) or() (func(*LogLine) bool, error) {
+	fn, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	fs := []func(*LogLine) bool{fn}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.take()
+		if fn, err = p.and(); err != nil {
+			return nil, err
+		}
+		fs = append(fs, fn)
+	}
+
+	if len(fs) == 1 {
+		return fs[0], nil
+	}
+	return Or(fs...), nil
+}
+
+//and parses: unary ("&&" unary)*
+func (p *exprParser) and() (func(*LogLine) bool, error) {
+	fn, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	fs := []func(*LogLine) bool{fn}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.take()
+		if fn, err = p.unary(); err != nil {
+			return nil, err
+		}
+		fs = append(fs, fn)
+	}
+
+	if len(fs) == 1 {
+		return fs[0], nil
+	}
+	return And(fs...), nil
+}
+
+//unary parses: "!" unary | "(" or ")" | "rules" | comparison
+func (p *exprParser) unary() (func(*LogLine) bool, error) {
+	t := p.peek()
+
+	switch {
+	case t.kind == tokOp && t.text == "!":
+		p.take()
+		fn, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(fn), nil
+
+	case t.kind == tokOp && t.text == "(":
+		p.take()
+		fn, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.take(); c.kind != tokOp || c.text != ")" {
+			return nil, p.errorf(c, "expected \")\" found %s", c)
+		}
+		return fn, nil
+
+	case t.kind == tokIdent && strings.ToLower(t.text) == "rules":
+		p.take()
+		f := p.filter
+		if p.explain {
+			return func(l *LogLine) bool {
+				writeout, _ := f.load().evaluate(l, nil)
+				return writeout
+			}, nil
+		}
+		return func(l *LogLine) bool {
+			return f.load().match(l)
+		}, nil
+	}
+
+	return p.comparison()
+}
+
+//comparison parses: field operator value
+func (p *exprParser) comparison() (func(*LogLine) bool, error) {
+	field := p.take()
+	if field.kind != tokIdent {
+		return nil, p.errorf(field, "expected a field found %s", field)
+	}
+
+	op := p.take()
+	if op.kind != tokOp && !(op.kind == tokIdent && (op.text == "contains" || op.text == "matches")) {
+		return nil, p.errorf(op, "expected an operator found %s", op)
+	}
+
+	v := p.take()
+
+	switch strings.ToLower(field.text) {
+	case "level":
+		return p.compareLevel(op, v)
+	case "line":
+		return p.compareLine(op, v)
+	case "time", "timestamp":
+		return p.compareTime(op, v)
+	case "file":
+		return p.compareString(op, v, true, func(l *LogLine) string { return l.File })
+	case "package":
+		return p.compareString(op, v, false, func(l *LogLine) string { return l.Package })
+	case "function":
+		return p.compareString(op, v, false, func(l *LogLine) string { return l.Function })
+	case "msg", "message":
+		return p.compareString(op, v, false, func(l *LogLine) string { return l.Message })
+	}
+
+	if strings.HasPrefix(field.text, "field.") && len(field.text) > len("field.") {
+		key := field.text[len("field."):]
+		return p.compareString(op, v, false, func(l *LogLine) string {
+			s, _ := l.Fields.Get(key)
+			return s
+		})
+	}
+	return nil, p.errorf(field, "unknown field %s", field)
+}
+
+//compareOrdered returns the comparison for op of c, the result of comparing
+//the field with the value (-1, 0 or 1).
+func (p *exprParser) compareOrdered(op token, c func(*LogLine) int) (func(*LogLine) bool, error) {
+	switch op.text {
+	case "==":
+		return func(l *LogLine) bool { return c(l) == 0 }, nil
+	case "!=":
+		return func(l *LogLine) bool { return c(l) != 0 }, nil
+	case "<":
+		return func(l *LogLine) bool { return c(l) < 0 }, nil
+	case "<=":
+		return func(l *LogLine) bool { return c(l) <= 0 }, nil
+	case ">":
+		return func(l *LogLine) bool { return c(l) > 0 }, nil
+	case ">=":
+		return func(l *LogLine) bool { return c(l) >= 0 }, nil
+	}
+	return nil, p.errorf(op, "operator %s not supported for this field", op)
+}
+
+func (p *exprParser) compareLevel(op, v token) (func(*LogLine) bool, error) {
+	if v.kind != tokIdent && v.kind != tokString {
+		return nil, p.errorf(v, "expected a level found %s", v)
+	}
+
+	lvl, err := specLevel(v.text)
+	if err != nil {
+		return nil, p.errorf(v, "%v", err)
+	}
+
+	return p.compareOrdered(op, func(l *LogLine) int {
+		return rank(l.Level) - rank(lvl)
+	})
+}
+
+func (p *exprParser) compareLine(op, v token) (func(*LogLine) bool, error) {
+	if v.kind != tokNumber {
+		return nil, p.errorf(v, "expected a number found %s", v)
+	}
+
+	n, err := strconv.Atoi(v.text)
+	if err != nil {
+		return nil, p.errorf(v, "%v", err)
+	}
+
+	return p.compareOrdered(op, func(l *LogLine) int {
+		return l.Line - n
+	})
+}
+
+func (p *exprParser) compareTime(op, v token) (func(*LogLine) bool, error) {
+	if v.kind != tokString {
+		return nil, p.errorf(v, "expected a quoted time found %s", v)
+	}
+
+	var t time.Time
+	var err error
+	for _, layout := range []string{time.RFC3339Nano, "2006/01/02 15:04:05.999999", "2006-01-02"} {
+		if t, err = time.Parse(layout, v.text); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, p.errorf(v, "invalid time %s", v)
+	}
+
+	return p.compareOrdered(op, func(l *LogLine) int {
+		switch {
+		case l.Timestamp.Before(t):
+			return -1
+		case l.Timestamp.After(t):
+			return 1
+		}
+		return 0
+	})
+}
+
+//compareString returns the comparison of the string field, pattern sets if ~
+//uses a package pattern rather than a regular expression.
+func (p *exprParser) compareString(op, v token, pattern bool, field func(*LogLine) string) (func(*LogLine) bool, error) {
+	if v.kind != tokString {
+		return nil, p.errorf(v, "expected a quoted string found %s", v)
+	}
+	s := v.text
+
+	switch op.text {
+	case "==":
+		return func(l *LogLine) bool { return field(l) == s }, nil
+	case "!=":
+		return func(l *LogLine) bool { return field(l) != s }, nil
+	case "contains":
+		return func(l *LogLine) bool { return strings.Contains(field(l), s) }, nil
+	case "~":
+		if pattern {
+			return FileMatches(s), nil
+		}
+		fallthrough
+	case "matches":
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, p.errorf(v, "%v", err)
+		}
+		return func(l *LogLine) bool { return re.MatchString(field(l)) }, nil
+	}
+	return nil, p.errorf(op, "operator %s not supported for this field", op)
+}
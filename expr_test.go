@@ -0,0 +1,121 @@
+package logfilter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestCompileExpr(t *testing.T) {
+	ts, _ := time.Parse("2006/01/02 15:04:05", "2009/01/23 01:23:23")
+
+	tests := []struct {
+		expr string
+		out  bool
+	}{
+		{`level >= warning || (file ~ "acme/db" && msg contains "slow query")`, true},
+		{`level >= warning`, false},
+		{`level == Debug && line < 30 && line != 23`, false},
+		{`!(level > debug) && line == 23`, true},
+		{`file ~ "acme/web"`, false},
+		{`msg matches "^slow .* took=[0-9]+ms$"`, true},
+		{`msg ~ "query"`, true},
+		{`package == "github.com/acme/db" && function contains "Acquire"`, true},
+		{`time >= "2009/01/23 00:00:00" && time < "2009-01-24"`, true},
+		{`time > "2009-01-24T00:00:00Z"`, false},
+	}
+
+	l := logfilter.LogLine{
+		Timestamp: ts,
+		File:      "/src/acme/db/pool.go",
+		Line:      23,
+		Message:   "slow query took=100ms",
+		Package:   "github.com/acme/db",
+		Function:  "(*Pool).Acquire",
+		Level:     logfilter.Debug,
+	}
+
+	for _, test := range tests {
+		fn, err := logfilter.CompileExpr(test.expr)
+		if err != nil {
+			t.Errorf("Unexpected error compiling %q: %v", test.expr, err)
+			continue
+		}
+		if fn(&l) != test.out {
+			t.Errorf("Expression %q expected %t, actual %t", test.expr, test.out, !test.out)
+		}
+	}
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{`level >= loud`, 10},
+		{`level >= warning ||`, 20},
+		{`(level >= warning`, 18},
+		{`colour == "red"`, 1},
+		{`msg contains "unterminated`, 14},
+		{`line ~ 3`, 6},
+		{`level >= warning $`, 18},
+		{`msg matches "("`, 13},
+	}
+
+	for _, test := range tests {
+		_, err := logfilter.CompileExpr(test.expr)
+		e, ok := err.(*logfilter.ExprError)
+		if !ok {
+			t.Errorf("Expected expression error compiling %q, actual %v", test.expr, err)
+			continue
+		}
+		if e.Pos != test.pos {
+			t.Errorf("Expression %q expected error at %d, actual %s", test.expr, test.pos, e)
+		}
+	}
+}
+
+func TestFilterExpr(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Warning)
+
+	if err := f.SetExpr(`rules || msg contains "tenant=42"`); err != nil {
+		t.Fatalf("Unexpected error setting expression: %v", err)
+	}
+
+	l := logfilter.LogLine{File: "/src/acme/db/db.go", Level: logfilter.Debug, Message: "login tenant=42"}
+	if !f.Match(&l) {
+		t.Errorf("Expression %q expected %t, actual %t", f.Expr(), true, false)
+	}
+
+	if err := f.SetExpr(`rules ||`); err == nil || f.Expr() != `rules || msg contains "tenant=42"` {
+		t.Errorf("Invalid expression expected error and no change, actual %v %q", err, f.Expr())
+	}
+
+	h := f.Handler()
+	req := httptest.NewRequest("PUT", "/expr", strings.NewReader(`{"expr":""}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || f.Expr() != "" || f.Match(&l) {
+		t.Errorf("Clearing expression expected rules to decide, actual %d %q", rec.Code, f.Expr())
+	}
+}
+
+func TestFilterExprReset(t *testing.T) {
+	f := logfilter.NewFilter()
+	if err := f.SetExpr("level >= error"); err != nil {
+		t.Fatalf("Unexpected error setting expression: %v", err)
+	}
+
+	f.Reset()
+
+	l := logfilter.LogLine{File: "/src/acme/db/db.go", Level: logfilter.Debug}
+	if f.Expr() != "" || !f.Match(&l) {
+		t.Errorf("Reset expected no expression and the line written, actual %q %t", f.Expr(), f.Match(&l))
+	}
+}
@@ -0,0 +1,218 @@
+package logfilter
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+//Field is a key=value pair from the message of a line.
+type Field struct {
+	Key   string
+	Value string
+}
+
+//Fields are the structured fields of a line in the order they were found.
+type Fields []Field
+
+//Get returns the value of the first field with the key and true, or "" and
+//false if there isn't one.
+func (f Fields) Get(key string) (string, bool) {
+	for _, fl := range f {
+		if fl.Key == key {
+			return fl.Value, true
+		}
+	}
+	return "", false
+}
+
+//String returns the fields in the logfmt format, i.e. user=42 ip=10.0.0.1.
+func (f Fields) String() string {
+	var b []byte
+	for i, fl := range f {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = appendLogfmt(b, fl.Key, fl.Value)
+	}
+	return string(b)
+}
+
+//appendLogfmt appends the key=value pair, quoting the value if required.
+func appendLogfmt(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	b = append(b, '=')
+	if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+		return strconv.AppendQuote(b, value)
+	}
+	return append(b, value...)
+}
+
+//The FieldParser func type extracts the structured fields from the message of
+//a line, the message itself is left unchanged. Field parsers are run after the
+//level Parsers so they see the message without the level.
+type FieldParser func(string) Fields
+
+//LogfmtFields is a FieldParser for logfmt style key=value pairs, values
+//containing spaces are quoted. Words which aren't pairs are ignored.
+//i.e. user login user=42 ip=10.0.0.1 agent="curl/7.1 (linux)"
+func LogfmtFields(m string) Fields {
+	_, f := splitLogfmt(m)
+	return f
+}
+
+//splitLogfmt splits the message into the words which aren't key=value pairs,
+//joined by single spaces, and the pairs.
+func splitLogfmt(m string) (string, Fields) {
+	var words []string
+	var f Fields
+
+	for i := 0; i < len(m); {
+		if isSpace(m[i]) {
+			i++
+			continue
+		}
+
+		//A key runs to the = of a pair, otherwise the whole word is text.
+		s := i
+		for i < len(m) && !isSpace(m[i]) && m[i] != '=' && m[i] != '"' {
+			i++
+		}
+
+		if i > s && i < len(m) && m[i] == '=' {
+			key := m[s:i]
+			v, n := logfmtValue(m[i+1:])
+			f = append(f, Field{Key: key, Value: v})
+			i += 1 + n
+			continue
+		}
+
+		_, n := logfmtValue(m[s:])
+		i = s + n
+		if i == s {
+			i++
+		}
+		words = append(words, m[s:i])
+	}
+
+	return strings.Join(words, " "), f
+}
+
+//logfmtValue returns the value at the start of s, unquoted if it is quoted,
+//and the number of bytes it takes up.
+func logfmtValue(s string) (string, int) {
+	if strings.HasPrefix(s, `"`) {
+		if j := closing(s); j > 0 {
+			if v, err := strconv.Unquote(s[:j+1]); err == nil {
+				return v, j + 1
+			}
+			return s[1:j], j + 1
+		}
+	}
+
+	i := 0
+	for i < len(s) && !isSpace(s[i]) {
+		i++
+	}
+	return s[:i], i
+}
+
+//isSpace returns true for the white space separating logfmt pairs.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+//FieldParsers returns the field parsers in use by the std logger.
+func FieldParsers() []FieldParser {
+	return std.FieldParsers()
+}
+
+//FieldParsers returns a copy of the field parsers in use by the logger.
+func (l *Logger) FieldParsers() []FieldParser {
+	return append([]FieldParser(nil), l.config().fieldParsers...)
+}
+
+//SetFieldParsers sets the field parsers used by the std logger.
+func SetFieldParsers(p []FieldParser) {
+	std.SetFieldParsers(p)
+}
+
+//SetFieldParsers sets the field parsers used by the logger to fill in the
+//Fields of each line, by default there are none. The slice is copied.
+func (l *Logger) SetFieldParsers(p []FieldParser) {
+	p = append([]FieldParser(nil), p...)
+	l.update(func(c *config) { c.fieldParsers = p })
+}
+
+//The StructuredParser func type is a Parser for structured messages, such as
+//JSON objects, which returns the fields of the message as well as its level
+//and text. Structured parsers are run before the Parsers, a line they give a
+//level to isn't passed to the Parsers.
+type StructuredParser func(string) (Level, string, Fields)
+
+//StructuredParsers returns the structured parsers in use by the std logger.
+func StructuredParsers() []StructuredParser {
+	return std.StructuredParsers()
+}
+
+//StructuredParsers returns a copy of the structured parsers in use by the
+//logger.
+func (l *Logger) StructuredParsers() []StructuredParser {
+	return append([]StructuredParser(nil), l.config().structuredParsers...)
+}
+
+//SetStructuredParsers sets the structured parsers used by the std logger.
+func SetStructuredParsers(p []StructuredParser) {
+	std.SetStructuredParsers(p)
+}
+
+//SetStructuredParsers sets the structured parsers used by the logger, by
+//default there are none. The slice is copied.
+//i.e. l.SetStructuredParsers([]logfilter.StructuredParser{logfilter.JSONParser})
+func (l *Logger) SetStructuredParsers(p []StructuredParser) {
+	p = append([]StructuredParser(nil), p...)
+	l.update(func(c *config) { c.structuredParsers = p })
+}
+
+//LogfmtFormat generates output in the logfmt format, the time and file are
+//included as the flags say followed by the level, the words of the message
+//which aren't fields and the fields.
+//time=2006-01-02T15:04:05Z file=main.go:10 level=info msg="user login" user=42
+func LogfmtFormat(prefix string, l *LogLine, f int) []byte {
+	var b []byte
+	b = append(b, prefix...)
+
+	if f&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		layout := "2006-01-02T15:04:05Z07:00"
+		if f&log.Lmicroseconds != 0 {
+			layout = "2006-01-02T15:04:05.000000Z07:00"
+		}
+		b = appendLogfmt(b, "time", l.Timestamp.Format(layout))
+		b = append(b, ' ')
+	}
+
+	if f&(log.Lshortfile|log.Llongfile) != 0 {
+		file := l.File
+		if f&log.Lshortfile != 0 {
+			if i := strings.LastIndex(file, "/"); i >= 0 {
+				file = file[i+1:]
+			}
+		}
+		b = appendLogfmt(b, "file", file+":"+strconv.Itoa(l.Line))
+		b = append(b, ' ')
+	}
+
+	msg := strings.TrimSpace(l.Message)
+	if len(l.Fields) > 0 {
+		msg, _ = splitLogfmt(msg)
+	}
+
+	b = appendLogfmt(b, "level", strings.ToLower(LevelToString(l.Level)))
+	b = append(b, ' ')
+	b = appendLogfmt(b, "msg", msg)
+	for _, fl := range l.Fields {
+		b = append(b, ' ')
+		b = appendLogfmt(b, fl.Key, fl.Value)
+	}
+	return append(b, '\n')
+}
@@ -0,0 +1,77 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestLogfmtFields(t *testing.T) {
+	tests := []struct {
+		m        string
+		expected logfilter.Fields
+	}{
+		{"user login user=42 ip=10.0.0.1 took=12ms\n", logfilter.Fields{{"user", "42"}, {"ip", "10.0.0.1"}, {"took", "12ms"}}},
+		{`request agent="curl/7.1 (linux)" path=/`, logfilter.Fields{{"agent", "curl/7.1 (linux)"}, {"path", "/"}}},
+		{`empty= quoted="a \"b\""`, logfilter.Fields{{"empty", ""}, {"quoted", `a "b"`}}},
+		{"no fields here", nil},
+		{`"quoted = text" =value`, nil},
+	}
+
+	for _, test := range tests {
+		if f := logfilter.LogfmtFields(test.m); !reflect.DeepEqual(f, test.expected) {
+			t.Errorf("Fields of %q expected %v, actual %v", test.m, test.expected, f)
+		}
+	}
+}
+
+func TestFieldFilters(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetFieldParsers([]logfilter.FieldParser{logfilter.LogfmtFields})
+	l.SetFilterFunc(logfilter.Or(
+		logfilter.FieldEquals("user", "42"),
+		logfilter.FieldMatches("took", regexp.MustCompile(`^[0-9]{4,}ms$`)),
+	))
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println("Info: user login user=42 ip=10.0.0.1")
+	lg.Println("Info: user login user=7 ip=10.0.0.2")
+	lg.Println("Info: slow query took=1500ms")
+
+	//The message is written out unchanged.
+	expected := "Info: user login user=42 ip=10.0.0.1\nInfo: slow query took=1500ms\n"
+	if b.String() != expected {
+		t.Errorf("Field Filter Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+
+	fn, err := logfilter.NewFilter().CompileExpr(`field.user == "42" && field.missing == ""`)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !fn(&logfilter.LogLine{Fields: logfilter.Fields{{"user", "42"}}}) {
+		t.Errorf("Expected field expression to match")
+	}
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetFieldParsers([]logfilter.FieldParser{logfilter.LogfmtFields})
+	l.SetFormatter(logfilter.LogfmtFormat)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println(`Warning: user login user=42 agent="curl/7.1 (linux)"`)
+	lg.Println("Info: no fields")
+
+	expected := `level=warning msg="user login" user=42 agent="curl/7.1 (linux)"` + "\n" + `level=info msg="no fields"` + "\n"
+	if b.String() != expected {
+		t.Errorf("Logfmt Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+}
@@ -1,122 +1,453 @@
 package logfilter
 
 import (
-	"strings"
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-//filter represents an individual log message filter.
+//filter represents an individual log message filter. It applies to lines
+//with a level from min to max (inclusive).
 type filter struct {
 	find      string
+	pat       pattern
 	inclusive bool
-	lvl       Level
+	min       Level
+	max       Level
+
+	//contains and re restrict the filter to lines with a matching message.
+	contains string
+	re       *regexp.Regexp
+
+	//expires is when a temporary filter is replaced by prev (or removed if
+	//prev is nil), zero for a permanent filter.
+	expires time.Time
+	prev    *filter
+
+	//sample keeps a proportion of the lines the filter writes out, nil for all.
+	sample *sampler
+
+	//limit rate limits the lines the filter writes out, nil for no limit.
+	limit *limiter
+}
+
+//newFilter creates a filter for the package name set to the level as When.
+func newFilter(find string, inclusive bool, l Level) filter {
+	f := filter{
+		find:      find,
+		inclusive: inclusive,
+	}
+	f.when(l)
+	return f
+}
+
+//when sets the band of levels the filter applies to for When(l). Inclusive
+//filters apply to the level and above, exclusive filters to the level and below.
+func (f *filter) when(l Level) {
+	if f.inclusive {
+		f.min, f.max = l, Off
+	} else {
+		f.min, f.max = Undefined, l
+	}
+}
+
+//level returns the levels the filter applies to, as the single level passed to
+//When if that is how it was set otherwise as min..max.
+func (f filter) level() string {
+	switch {
+	case f.inclusive && f.max == Off:
+		return LevelToString(f.min)
+	case !f.inclusive && f.min == Undefined:
+		return LevelToString(f.max)
+	}
+	return LevelToString(f.min) + ".." + LevelToString(f.max)
+}
+
+//rules is a snapshot of the filters being applied. A published snapshot is
+//never modified, changes are made to a copy which then replaces it.
+type rules []filter
+
+//key identifies the filter within the rules, the package name followed by
+//any message condition.
+func (f filter) key() string {
+	return f.find + f.message()
+}
+
+//find returns the index of the filter with the key or -1.
+func (r rules) find(key string) int {
+	for i := range r {
+		if r[i].key() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+//set replaces the filter with the same key or appends it.
+func (r rules) set(f filter) rules {
+	f.pat = compilePattern(f.find)
+
+	if i := r.find(f.key()); i >= 0 {
+		r[i] = f
+		return r
+	}
+	return append(r, f)
+}
+
+//defaultRules returns the rules of an empty filter, everything is written out.
+func defaultRules() rules {
+	return rules{newFilter("", true, Undefined)}
+}
+
+//Filter is a table of include/exclude rules used to decide which lines are
+//written out. Each Logger owns a Filter, the package level Include, Exclude and
+//Default functions change the Filter of the standard logger. The rules are
+//held as a snapshot which is replaced atomically so a Filter can be changed
+//while it is in use.
+type Filter struct {
+	mu sync.Mutex   // serialises changes to the rules.
+	v  atomic.Value // rules
+	x  atomic.Value // *filterExpr, see SetExpr.
+	s  atomic.Value // levelSamplers, see SampleLevel.
+}
+
+//NewFilter creates a new Filter which writes out everything until rules are
+//added.
+func NewFilter() *Filter {
+	f := &Filter{}
+	f.Reset()
+	return f
+}
+
+//load returns the current rules snapshot.
+func (f *Filter) load() rules {
+	r, _ := f.v.Load().(rules)
+	return r
+}
+
+//update applies fn to a copy of the current rules and publishes the result.
+func (f *Filter) update(fn func(rules) rules) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.v.Store(fn(append(rules(nil), f.load()...)))
 }
 
 //filters provides a nicer API by allowing us to create the When function.
-type filters []*filter
+//It holds the keys of the filters so the level can be set later and the state
+//they replaced so For can revert to it.
+type filters struct {
+	set  *Filter
+	keys []string
+	prev []*filter
+}
 
 //Default sets the logging level the is output by all packages.
 func Default(lvl Level) {
-	f := findFilter("")
-	f.lvl = lvl
+	stdFilters.Default(lvl)
 }
 
-//findFilter is a helper function to find a filter by package name.
-func findFilter(pkg string) *filter {
-	for i := range stdFilters {
-		if stdFilters[i].find == pkg {
-			return stdFilters[i]
+//Default sets the logging level the is output by all packages.
+func (f *Filter) Default(lvl Level) {
+	f.update(func(r rules) rules {
+		if i := r.find(""); i >= 0 {
+			r[i].when(lvl)
 		}
-	}
-	return nil
+		return r
+	})
 }
 
-//Include adds filter object(s) to the standard filter and returns pointers to
-//the newly created object to allow you to set the required level. As default it
-//sets the level to undefined (i.e. lowest).
-func Include(packagenames ...string) filters {
-	n := filters{}
-
-	for i := range packagenames {
-		f := findFilter(packagenames[i])
-		if f != nil {
-			f.inclusive = true
-			f.lvl = Undefined
-			n = append(n, f)
-		} else {
-			f = &filter{
-				find:      packagenames[i],
-				inclusive: true,
-				lvl:       Undefined,
+//add creates or replaces a copy of the filter for each of the package names.
+func (f *Filter) add(n filter, packagenames []string) filters {
+	keys := make([]string, len(packagenames))
+	prev := make([]*filter, len(packagenames))
+
+	f.update(func(r rules) rules {
+		for i, pkg := range packagenames {
+			n.find = pkg
+			keys[i] = n.key()
+
+			if j := r.find(keys[i]); j >= 0 {
+				p := r[j]
+				prev[i] = &p
 			}
-			n = append(n, f)
-			stdFilters = append(stdFilters, f)
+			r = r.set(n)
 		}
+		return r
+	})
+
+	return filters{
+		set:  f,
+		keys: keys,
+		prev: prev,
 	}
+}
 
-	return n
+//Include adds filter object(s) to the standard filter and returns them to allow
+//you to set the required level. As default it sets the level to undefined (i.e. lowest).
+func Include(packagenames ...string) filters {
+	return stdFilters.Include(packagenames...)
 }
 
-//Exclude adds filter object(s) to the standard filter and returns pointers to
-//the newly created object to allow you to set the required level. As default it
-//sets the level to off (i.e. highest).
+//Include adds filter object(s) to the filter and returns them to allow you to
+//set the required level. As default it sets the level to undefined (i.e. lowest).
+func (f *Filter) Include(packagenames ...string) filters {
+	return f.add(newFilter("", true, Undefined), packagenames)
+}
+
+//Exclude adds filter object(s) to the standard filter and returns them to allow
+//you to set the required level. As default it sets the level to off (i.e. highest).
 func Exclude(packagenames ...string) filters {
-	n := filters{}
-
-	for i := range packagenames {
-		f := findFilter(packagenames[i])
-		if f != nil {
-			f.inclusive = false
-			f.lvl = Off
-			n = append(n, f)
-		} else {
-			f = &filter{
-				find:      packagenames[i],
-				inclusive: false,
-				lvl:       Off,
+	return stdFilters.Exclude(packagenames...)
+}
+
+//Exclude adds filter object(s) to the filter and returns them to allow you to
+//set the required level. As default it sets the level to off (i.e. highest).
+func (f *Filter) Exclude(packagenames ...string) filters {
+	return f.add(newFilter("", false, Off), packagenames)
+}
+
+//When sets the level on the filters provided. Included filters write out lines
+//of the level and above, excluded filters drop lines of the level and below.
+func (f filters) When(l Level) filters {
+	f.set.update(func(r rules) rules {
+		for _, k := range f.keys {
+			if i := r.find(k); i >= 0 {
+				r[i].when(l)
 			}
-			n = append(n, f)
-			stdFilters = append(stdFilters, f)
 		}
+		return r
+	})
+	return f
+}
+
+//Between sets the band of levels the filters provided apply to, from min to
+//max inclusive. Included filters write out lines in the band and excluded
+//filters drop them, lines outside the band are left to the less specific
+//filters. If min is above max they are swapped, so the band is the same
+//whichever order they are given in.
+//i.e. Exclude("pkg").Between(logfilter.Trace, logfilter.Debug)
+func (f filters) Between(min, max Level) filters {
+	if rank(min) > rank(max) {
+		min, max = max, min
 	}
 
-	return n
+	f.set.update(func(r rules) rules {
+		for _, k := range f.keys {
+			if i := r.find(k); i >= 0 {
+				r[i].min, r[i].max = min, max
+			}
+		}
+		return r
+	})
+	return f
+}
+
+//For makes the filters provided temporary, after the duration they revert to
+//the state they were in before Include or Exclude was called.
+//i.e. Include("pkg").When(logfilter.Trace).For(15 * time.Minute)
+func (f filters) For(d time.Duration) filters {
+	return f.expireAfter(d, nil)
 }
 
-//When sets the level on the filters provided.
-func (f filters) When(l Level) {
-	for i := range f {
-		f[i].lvl = l
-	}
+//expireAfter implements For, calling done (if not nil) once the filters have
+//reverted.
+func (f filters) expireAfter(d time.Duration, done func()) filters {
+	expires := time.Now().Add(d)
+
+	f.set.update(func(r rules) rules {
+		for i, k := range f.keys {
+			if j := r.find(k); j >= 0 {
+				r[j].expires = expires
+				r[j].prev = f.prev[i]
+			}
+		}
+		return r
+	})
+
+	time.AfterFunc(d, func() {
+		f.set.expire(f.keys, expires)
+		if done != nil {
+			done()
+		}
+	})
+	return f
+}
+
+//expire reverts the filters with the keys which are set to expire at the time
+//provided. Filters which have been changed since are left alone.
+func (f *Filter) expire(keys []string, expires time.Time) {
+	now := time.Now()
+
+	f.update(func(r rules) rules {
+		for _, k := range keys {
+			i := r.find(k)
+			if i < 0 || !r[i].expires.Equal(expires) {
+				continue
+			}
+
+			//Skip any previous state which has also expired.
+			p := r[i].prev
+			for p != nil && !p.expires.IsZero() && !p.expires.After(now) {
+				p = p.prev
+			}
+
+			if p != nil {
+				r[i] = *p
+			} else {
+				r = append(r[:i], r[i+1:]...)
+			}
+		}
+		return r
+	})
+}
+
+//Remove deletes the filters for the package names, message filters are named
+//in the spec format (i.e. acme/db~"connection reset"). The default filter
+//can't be removed, use Default to change it.
+func (f *Filter) Remove(packagenames ...string) {
+	f.update(func(r rules) rules {
+		for _, pkg := range packagenames {
+			if i := r.find(pkg); pkg != "" && i >= 0 {
+				r = append(r[:i], r[i+1:]...)
+			}
+		}
+		return r
+	})
 }
 
 //StdFilterReset resets the filters being applied, which is useful for testing.
 func StdFilterReset() {
-	stdFilters = filters([]*filter{
-		{
-			find:      "",
-			inclusive: true,
-			lvl:       Undefined,
-		},
+	stdFilters.Reset()
+}
+
+//Reset removes all the rules, level samples and any expression from the
+//filter leaving the default of writing out everything.
+func (f *Filter) Reset() {
+	f.update(func(rules) rules {
+		return defaultRules()
 	})
+	f.setLevelSamplers(levelSamplers{})
+	f.x.Store(&filterExpr{})
 }
 
 //StdFilter is the default implementation used by logger for filtering.
 //Returns true if the line is written out.
 func StdFilter(l *LogLine) bool {
+	return stdFilters.Match(l)
+}
+
+//Match returns true if the line is written out by the filter. It has the
+//signature required by SetFilterFunc.
+func (f *Filter) Match(l *LogLine) bool {
+	var writeout bool
+	if x := f.expr(); x != nil {
+		writeout = x.fn(l)
+	} else {
+		writeout = f.load().match(l)
+	}
+
+	if s := f.levelSamplers()[l.Level]; writeout && s != nil && !s.keep(l) {
+		l.thinned = true
+		return false
+	}
+	return writeout
+}
+
+//EffectiveLevel returns the lowest level written out by the filter for lines
+//from the file, or Off if nothing is written out. Samples and rate limits
+//aren't applied, so it doesn't change what is written out and the level of a
+//sampled or limited rule is reported as written.
+func (f *Filter) EffectiveLevel(file string) Level {
+	x := f.expr()
+	r := f.load()
+
+	for _, lvl := range Levels() {
+		l := LogLine{File: file, Level: lvl}
+
+		var writeout bool
+		if x != nil {
+			writeout = x.explain(&l)
+		} else {
+			writeout, _ = r.evaluate(&l, nil)
+		}
+		if writeout {
+			return lvl
+		}
+	}
+	return Off
+}
+
+//match returns true if the line is written out by the rules. Of the filters
+//which match the package and whose band includes the level the most specific
+//decides, if they are equally specific the first added decides. A line the
+//deciding filter writes out is then subject to its sample and rate limit.
+func (r rules) match(l *LogLine) bool {
+	writeout, i := r.evaluate(l, nil)
+	if !writeout {
+		return false
+	}
+	if r[i].sample != nil && !r[i].sample.keep(l) {
+		l.thinned = true
+		return false
+	}
+	if r[i].limit != nil && !r[i].limit.allow(l) {
+		l.thinned = true
+		return false
+	}
+	return true
+}
+
+//evaluate implements match, returning the decision of the rules and the index
+//of the deciding filter (or -1). When e isn't nil every filter is evaluated and
+//recorded in it.
+func (r rules) evaluate(l *LogLine, e *Explanation) (bool, int) {
 	depth := -1
 	writeout := false
+	winner := -1
+	file := splitFile(l.File)
+	sym := splitSymbol(l)
+	reg := registeredLevels()
+	lr := reg.rank(l.Level)
 
 	//Check for Exclusions / Inclusions
-	for i := range stdFilters {
+	for i := range r {
+		d := r[i].specificity()
+		if e == nil && d <= depth {
+			continue
+		}
 
 		//Does the filter apply.
-		if len(stdFilters[i].find) > depth &&
-			strings.Contains(l.File, stdFilters[i].find) &&
-			((stdFilters[i].inclusive && stdFilters[i].lvl <= l.Level) ||
-				(!stdFilters[i].inclusive && stdFilters[i].lvl >= l.Level)) {
-			writeout = stdFilters[i].inclusive
-			depth = len(stdFilters[i].find)
+		pkg := r[i].pat.match(file, sym)
+		lvl := reg.rank(r[i].min) <= lr && lr <= reg.rank(r[i].max)
+		msg := (pkg && lvl || e != nil) && r[i].matchMessage(l.Message)
+		if e != nil {
+			e.Rules = append(e.Rules, RuleExplanation{
+				Rule:        r[i].String(),
+				Package:     pkg,
+				Level:       lvl,
+				Message:     msg,
+				Specificity: d,
+			})
+		}
+
+		if d > depth && pkg && lvl && msg {
+			writeout = r[i].inclusive
+			depth = d
+			winner = i
 		}
 	}
-	return writeout
+
+	if e != nil {
+		e.Written = writeout
+		if winner >= 0 {
+			e.Rules[winner].Won = true
+			e.Reason = fmt.Sprintf("rule %q is the most specific rule matching the package whose levels include %s", r[winner].String(), LevelToString(l.Level))
+		} else {
+			e.Reason = fmt.Sprintf("no rule matches the package with levels including %s", LevelToString(l.Level))
+		}
+	}
+	return writeout, winner
 }
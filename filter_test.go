@@ -0,0 +1,164 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestLoggerFilters(t *testing.T) {
+	var audit, debug bytes.Buffer
+
+	al := logfilter.New(&audit, "", 0)
+	al.Default(logfilter.Warning)
+
+	dl := logfilter.New(&debug, "", 0)
+	dl.Exclude("github.com/d2g/logfilter").When(logfilter.Trace)
+
+	for _, l := range []*logfilter.Logger{al, dl} {
+		lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+		lg.Println("Trace: Message")
+		lg.Println("Debug: Message")
+		lg.Println("Warning: Message")
+	}
+
+	if audit.String() != "Warning: Message\n" {
+		t.Errorf("Audit Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: Message\n", audit.String())
+	}
+
+	if debug.String() != "Debug: Message\nWarning: Message\n" {
+		t.Errorf("Debug Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Debug: Message\nWarning: Message\n", debug.String())
+	}
+
+	//The standard filter is unaffected.
+	l := logfilter.LogLine{File: "/a/b/c/d.go", Level: logfilter.Trace}
+	if !logfilter.StdFilter(&l) {
+		t.Errorf("Standard filter changed by logger filters")
+	}
+
+	al.Reset()
+	if !al.Filters().Match(&l) {
+		t.Errorf("Reset filter expected %t, actual %t", true, false)
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Include("acme/db").When(logfilter.Debug)
+	f.Default(logfilter.Error)
+
+	l := logfilter.LogLine{File: "/src/acme/db/pool.go", Level: logfilter.Debug}
+	if !f.Match(&l) {
+		t.Errorf("Expected %s line from %s to be written", logfilter.LevelToString(l.Level), l.File)
+	}
+
+	l.File = "/src/acme/web/server.go"
+	if f.Match(&l) {
+		t.Errorf("Expected %s line from %s to be filtered", logfilter.LevelToString(l.Level), l.File)
+	}
+
+	var b bytes.Buffer
+	lf := logfilter.New(&b, "", 0)
+	lf.SetFilters(f)
+	if lf.Filters() != f {
+		t.Errorf("Error setting filters expected %p, actual %p", f, lf.Filters())
+	}
+}
+
+func TestFilterFor(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Include("acme/db").When(logfilter.Warning)
+	f.Include("acme/db").When(logfilter.Trace).For(20 * time.Millisecond)
+	f.Include("acme/web").When(logfilter.Trace).For(20 * time.Millisecond)
+
+	if f.String() != "acme/db=trace,acme/web=trace" {
+		t.Errorf("Override Mismatch Expected:\"%s\" Actual:\"%s\"\n", "acme/db=trace,acme/web=trace", f.String())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if f.String() != "acme/db=warning" {
+		t.Errorf("Revert Mismatch Expected:\"%s\" Actual:\"%s\"\n", "acme/db=warning", f.String())
+	}
+}
+
+func TestFilterForChanged(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Include("acme/db").When(logfilter.Trace).For(20 * time.Millisecond)
+
+	//A later change isn't reverted by the earlier override expiring.
+	f.Include("acme/db").When(logfilter.Info)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if f.String() != "acme/db=info" {
+		t.Errorf("Mismatch Expected:\"%s\" Actual:\"%s\"\n", "acme/db=info", f.String())
+	}
+}
+
+func TestFilterBetween(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Warning)
+	f.Include("acme/db").Between(logfilter.Debug, logfilter.Info)
+	f.Exclude("acme/web").Between(logfilter.Debug, logfilter.Trace)
+
+	tests := []struct {
+		file  string
+		level logfilter.Level
+		out   bool
+	}{
+		{"/src/acme/db/db.go", logfilter.Trace, false},
+		{"/src/acme/db/db.go", logfilter.Debug, true},
+		{"/src/acme/db/db.go", logfilter.Info, true},
+		{"/src/acme/db/db.go", logfilter.Error, true},
+		{"/src/acme/web/web.go", logfilter.Debug, false},
+		{"/src/acme/web/web.go", logfilter.Info, false},
+		{"/src/acme/web/web.go", logfilter.Warning, true},
+	}
+
+	for _, test := range tests {
+		l := logfilter.LogLine{File: test.file, Level: test.level}
+		if f.Match(&l) != test.out {
+			t.Errorf("Band %s line from %s expected %t, actual %t", logfilter.LevelToString(test.level), test.file, test.out, !test.out)
+		}
+	}
+
+	e := "warning,acme/db=debug..info,-acme/web=trace..debug"
+	if f.String() != e {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\"\n", e, f.String())
+	}
+
+	g := logfilter.NewFilter()
+	if err := g.ApplySpec(e); err != nil || g.String() != e {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\" %v\n", e, g.String(), err)
+	}
+
+	//An inverted band is swapped, as it is by Between.
+	if err := g.ApplySpec("acme/db=info..debug"); err != nil || g.String() != "acme/db=debug..info" {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\" %v\n", "acme/db=debug..info", g.String(), err)
+	}
+}
+
+func TestEffectiveLevelLimit(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Warning)
+	f.Include("acme/db").When(logfilter.Debug).Limit(0, 1)
+
+	for i := 0; i < 3; i++ {
+		if lvl := f.EffectiveLevel("/src/acme/db/db.go"); lvl != logfilter.Debug {
+			t.Fatalf("EffectiveLevel expected %s, actual %s", logfilter.LevelToString(logfilter.Debug), logfilter.LevelToString(lvl))
+		}
+	}
+
+	if s := f.Suppressed(); len(s) != 0 {
+		t.Errorf("EffectiveLevel expected no suppressed lines, actual %v", s)
+	}
+
+	l := logfilter.LogLine{File: "/src/acme/db/db.go", Level: logfilter.Debug}
+	if !f.Match(&l) {
+		t.Errorf("EffectiveLevel expected to leave the token for the first line")
+	}
+}
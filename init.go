@@ -7,7 +7,7 @@ import (
 
 var std *Logger
 
-var stdFilters filters
+var stdFilters = &Filter{}
 
 func init() {
 	// Setup logging the way we expect to receive log messages
@@ -15,16 +15,21 @@ func init() {
 	// Remove the prefix.
 	log.SetPrefix("")
 	// Setup the standard logger.
-	std = &Logger{
-		flag:      log.LstdFlags,
-		output:    os.Stderr,
-		formatter: StdFormat,
-		parsers:   []Parser{StdParser},
-		filter:    StdFilter,
-	}
+	std = New(os.Stderr, "", log.LstdFlags)
+	std.update(func(c *config) {
+		c.filters = stdFilters
+		c.filter = StdFilter
+	})
 	// Set the output of the standard log package to our logger.
 	log.SetOutput(std)
 
 	//Reset the Standard Filter.
 	StdFilterReset()
+
+	//Apply any filter spec from the environment.
+	if spec := os.Getenv(SpecEnv); spec != "" {
+		if err := ApplySpec(spec); err != nil {
+			log.Printf("Warning: ignoring %s: %v", SpecEnv, err)
+		}
+	}
 }
@@ -3,6 +3,8 @@ package logfilter
 import (
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,9 +23,158 @@ const (
 	Off
 )
 
+// defaultLevelAliases are the abbreviations for the levels used by logutils,
+// log4j, syslog and others. They aren't ordinary words so text in a message
+// isn't mistaken for a level.
+var defaultLevelAliases = map[string]Level{
+	"trc":  Trace,
+	"trce": Trace,
+
+	"dbg":  Debug,
+	"debu": Debug,
+
+	"inf": Info,
+
+	"wrn":  Warning,
+	"warn": Warning,
+
+	"err":  Error,
+	"erro": Error,
+
+	"ftl":   Fatal,
+	"crit":  Fatal,
+	"emerg": Fatal,
+}
+
+// shortLevelAliases are the single letter aliases, see ShortLevelAliases.
+var shortLevelAliases = map[string]Level{
+	"t": Trace,
+	"d": Debug,
+	"i": Info,
+	"w": Warning,
+	"e": Error,
+	"f": Fatal,
+}
+
+// wordLevelAliases are the aliases which are also ordinary words, see
+// WordLevelAliases.
+var wordLevelAliases = map[string]Level{
+	"finer":  Trace,
+	"finest": Trace,
+
+	"fine": Debug,
+
+	"information":   Info,
+	"informational": Info,
+	"notice":        Info,
+
+	"severe": Error,
+
+	"critical":  Fatal,
+	"alert":     Fatal,
+	"emergency": Fatal,
+	"panic":     Fatal,
+}
+
+// ShortLevelAliases returns the single letter aliases (i.e. E for Error). They
+// aren't in the alias table by default as a letter in the text of a message is
+// easily taken for a level, add them with SetLevelAliases.
+func ShortLevelAliases() map[string]Level {
+	return copyAliases(shortLevelAliases)
+}
+
+// WordLevelAliases returns the aliases which are also ordinary words (i.e.
+// notice, panic or severe). They aren't in the alias table by default as Go
+// writes "panic: " itself, add them with SetLevelAliases.
+func WordLevelAliases() map[string]Level {
+	return copyAliases(wordLevelAliases)
+}
+
+var (
+	aliasMu sync.Mutex   // serialises changes to the aliases.
+	aliases atomic.Value // map[string]Level, never modified once stored.
+)
+
+// currentAliases returns the alias table, the defaults until it is changed.
+func currentAliases() map[string]Level {
+	if a, ok := aliases.Load().(map[string]Level); ok {
+		return a
+	}
+	return defaultLevelAliases
+}
+
+// copyAliases returns a copy of the alias table with lower case names.
+func copyAliases(a map[string]Level) map[string]Level {
+	c := make(map[string]Level, len(a))
+	for n, l := range a {
+		c[strings.ToLower(n)] = l
+	}
+	return c
+}
+
+// updateAliases applies fn to a copy of the alias table and stores the result.
+func updateAliases(fn func(map[string]Level)) {
+	aliasMu.Lock()
+	defer aliasMu.Unlock()
+
+	a := copyAliases(currentAliases())
+	fn(a)
+	aliases.Store(a)
+}
+
+// LevelAliases returns a copy of the table of alternative names for levels
+// consulted by StringToLevel (and so the parsers).
+func LevelAliases() map[string]Level {
+	return copyAliases(currentAliases())
+}
+
+// SetLevelAlias makes StringToLevel convert the alias (case insensitive) to the
+// level, i.e. SetLevelAlias("WRN", Warning).
+func SetLevelAlias(alias string, l Level) {
+	updateAliases(func(a map[string]Level) {
+		a[strings.ToLower(alias)] = l
+	})
+}
+
+// SetLevelAliases adds the aliases to the table, i.e.
+// SetLevelAliases(ShortLevelAliases()).
+func SetLevelAliases(a map[string]Level) {
+	updateAliases(func(c map[string]Level) {
+		for n, l := range a {
+			c[strings.ToLower(n)] = l
+		}
+	})
+}
+
+// RemoveLevelAlias removes the alias from the table.
+func RemoveLevelAlias(alias string) {
+	updateAliases(func(a map[string]Level) {
+		delete(a, strings.ToLower(alias))
+	})
+}
+
+// ResetLevelAliases restores the table to the default aliases and those of the
+// registered levels.
+func ResetLevelAliases() {
+	updateAliases(func(a map[string]Level) {
+		for n := range a {
+			delete(a, n)
+		}
+		for n, l := range defaultLevelAliases {
+			a[n] = l
+		}
+		for n, l := range registeredLevels().aliases {
+			a[n] = l
+		}
+	})
+}
+
 // StringToLevel converts a string log level (i.e. "Error") to the corresponding Level (i.e. Error).
+// Names which aren't levels, including those registered with RegisterLevel, are
+// looked up in the alias table (see SetLevelAlias).
 func StringToLevel(sl string) Level {
-	switch strings.ToLower(sl) {
+	sl = strings.ToLower(sl)
+	switch sl {
 	case "trace":
 		return Trace
 	case "debug":
@@ -39,7 +190,12 @@ func StringToLevel(sl string) Level {
 	case "off":
 		return Off
 	}
-	return Undefined
+
+	if l, ok := registeredLevels().levels[sl]; ok {
+		return l
+	}
+
+	return currentAliases()[sl]
 }
 
 // LevelToString converts a Level (i.e. Error) to the corresponding string version (i.e. "Error").
@@ -60,6 +216,10 @@ func LevelToString(l Level) string {
 	case Off:
 		return "Off"
 	}
+
+	if n, ok := registeredLevels().names[l]; ok {
+		return n
+	}
 	return "Undefined"
 }
 
@@ -0,0 +1,101 @@
+package logfilter_test
+
+import (
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestLevelAliases(t *testing.T) {
+	defer logfilter.ResetLevelAliases()
+
+	tests := []struct {
+		s     string
+		level logfilter.Level
+	}{
+		{"WARN", logfilter.Warning},
+		{"ERR", logfilter.Error},
+		{"DBG", logfilter.Debug},
+		{"CRIT", logfilter.Fatal},
+		{"TRC", logfilter.Trace},
+		{"E", logfilter.Undefined},
+		{"notice", logfilter.Undefined},
+		{"panic", logfilter.Undefined},
+		{"Warning", logfilter.Warning},
+		{"loud", logfilter.Undefined},
+	}
+
+	for _, test := range tests {
+		if l := logfilter.StringToLevel(test.s); l != test.level {
+			t.Errorf("StringToLevel(%q) expected %s, actual %s", test.s, logfilter.LevelToString(test.level), logfilter.LevelToString(l))
+		}
+	}
+
+	//The parsers use the aliases.
+	if l, m := logfilter.StdParser("ERRO: Failed"); l != logfilter.Error || m != "Failed" {
+		t.Errorf("StdParser expected %s %q, actual %s %q", logfilter.LevelToString(logfilter.Error), "Failed", logfilter.LevelToString(l), m)
+	}
+
+	if l, m := logfilter.SqrParser("[WARN] Slow"); l != logfilter.Warning || m != "Slow" {
+		t.Errorf("SqrParser expected %s %q, actual %s %q", logfilter.LevelToString(logfilter.Warning), "Slow", logfilter.LevelToString(l), m)
+	}
+
+	//The single letters and words are opt in.
+	logfilter.SetLevelAliases(logfilter.ShortLevelAliases())
+	logfilter.SetLevelAliases(logfilter.WordLevelAliases())
+
+	if l := logfilter.StringToLevel("E"); l != logfilter.Error {
+		t.Errorf("StringToLevel(%q) expected %s, actual %s", "E", logfilter.LevelToString(logfilter.Error), logfilter.LevelToString(l))
+	}
+	if l, m := logfilter.SqrParser("[w] Slow"); l != logfilter.Warning || m != "Slow" {
+		t.Errorf("SqrParser expected %s %q, actual %s %q", logfilter.LevelToString(logfilter.Warning), "Slow", logfilter.LevelToString(l), m)
+	}
+	if l := logfilter.StringToLevel("notice"); l != logfilter.Info {
+		t.Errorf("StringToLevel(%q) expected %s, actual %s", "notice", logfilter.LevelToString(logfilter.Info), logfilter.LevelToString(l))
+	}
+
+	//The table can be changed.
+	logfilter.SetLevelAlias("LOUD", logfilter.Error)
+	logfilter.RemoveLevelAlias("warn")
+
+	if l := logfilter.StringToLevel("loud"); l != logfilter.Error {
+		t.Errorf("StringToLevel(%q) expected %s, actual %s", "loud", logfilter.LevelToString(logfilter.Error), logfilter.LevelToString(l))
+	}
+	if l, _ := logfilter.StdParser("WARN: Message"); l != logfilter.Undefined {
+		t.Errorf("StdParser expected %s, actual %s", logfilter.LevelToString(logfilter.Undefined), logfilter.LevelToString(l))
+	}
+	if _, ok := logfilter.LevelAliases()["loud"]; !ok {
+		t.Errorf("Expected alias %q in LevelAliases", "loud")
+	}
+
+	logfilter.ResetLevelAliases()
+	if l := logfilter.StringToLevel("loud"); l != logfilter.Undefined {
+		t.Errorf("StringToLevel(%q) after reset expected %s, actual %s", "loud", logfilter.LevelToString(logfilter.Undefined), logfilter.LevelToString(l))
+	}
+	if l := logfilter.StringToLevel("e"); l != logfilter.Undefined {
+		t.Errorf("StringToLevel(%q) after reset expected %s, actual %s", "e", logfilter.LevelToString(logfilter.Undefined), logfilter.LevelToString(l))
+	}
+}
+
+func TestParsersIgnoreText(t *testing.T) {
+	tests := []struct {
+		m      string
+		parser logfilter.Parser
+	}{
+		{"items [e] processed", logfilter.SqrParser},
+		{"items [Error] processed", logfilter.SqrParser},
+		{"[i] item", logfilter.SqrParser},
+		{"e: value", logfilter.StdParser},
+		{"panic: runtime error: index out of range", logfilter.StdParser},
+	}
+
+	for _, test := range tests {
+		if l, m := test.parser(test.m); l != logfilter.Undefined || m != test.m {
+			t.Errorf("Parsing %q expected %s %q, actual %s %q", test.m, logfilter.LevelToString(logfilter.Undefined), test.m, logfilter.LevelToString(l), m)
+		}
+	}
+
+	if l, m := logfilter.SqrParser("  [Error] failed"); l != logfilter.Error || m != "failed" {
+		t.Errorf("SqrParser expected %s %q, actual %s %q", logfilter.LevelToString(logfilter.Error), "failed", logfilter.LevelToString(l), m)
+	}
+}
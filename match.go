@@ -0,0 +1,184 @@
+package logfilter
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+//pattern is a compiled package pattern used by a filter to match the file a
+//line was logged from. Patterns are matched against whole import path
+//segments rather than substrings:
+//	acme/db       the package acme/db and its subpackages.
+//	acme/db/...   the same as acme/db.
+//	acme/db$      only the package acme/db.
+//	acme/*/db     a glob, * matches a single segment (see path.Match).
+//	/src/acme/db  a leading / anchors the pattern to the start of the path.
+//	acme/db.Open  the function Open in the package acme/db.
+//
+//The function follows the first dot of the last segment, after any major
+//version (i.e. gopkg.in/yaml.v2.Marshal), and is matched as a glob except that
+//the * of a pointer receiver (i.e. (*Pool)) is literal.
+//
+//Patterns are also matched against the import path and function name of the
+//caller when they are known, a pattern naming a function
+//(i.e. github.com/acme/db.(*Pool).Acquire) only matches that way.
+//
+//When the file is in a vendor directory or the module cache the start of its
+//import path is known and patterns must match from there, unless they include
+//vendor. Module versions (i.e. db@v1.2.3) are ignored.
+type pattern struct {
+	segs     []string
+	sym      []string // segs with the package and function split.
+	literal  int
+	exact    bool
+	anchored bool
+	vendor   bool
+}
+
+//compilePattern converts the package name used by a filter to a pattern.
+func compilePattern(find string) pattern {
+	p := pattern{}
+
+	if strings.HasSuffix(find, "$") {
+		p.exact = true
+		find = find[:len(find)-1]
+	}
+	find = strings.TrimSuffix(find, "/...")
+
+	if strings.HasPrefix(find, "/") {
+		p.anchored = true
+	}
+
+	for _, s := range strings.Split(find, "/") {
+		if s == "" {
+			continue
+		}
+		if s == "vendor" {
+			p.vendor = true
+		}
+		p.segs = append(p.segs, s)
+	}
+
+	//The last segment may name a function within the package.
+	p.sym = p.segs
+	if n := len(p.segs); n > 1 {
+		if m := symbolPattern.FindStringSubmatch(p.segs[n-1]); m != nil && !majorVersion.MatchString(m[2]) {
+			fn := strings.Replace(m[2], "(*", `(\*`, -1)
+			p.sym = append(append([]string(nil), p.segs[:n-1]...), m[1], fn)
+		}
+	}
+
+	for _, s := range p.sym {
+		if !strings.ContainsAny(strings.Replace(s, `\*`, "", -1), `*?[\`) {
+			p.literal++
+		}
+	}
+
+	return p
+}
+
+//symbolPattern splits the last segment of a pattern into the package name,
+//including any major version, and the function.
+var symbolPattern = regexp.MustCompile(`^([^.]+(?:\.v[0-9]+)?)\.([(A-Za-z_].*)$`)
+
+//majorVersion matches the major version of a package such as gopkg.in/yaml.v2,
+//which isn't a function.
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+//specificity ranks the pattern against other patterns, the filter with the
+//most specific matching pattern decides if a line is written. Patterns with
+//more segments are more specific, then those with fewer globs and then exact
+//package patterns.
+func (p pattern) specificity() int {
+	s := len(p.sym)<<8 | p.literal<<1
+	if p.exact {
+		s |= 1
+	}
+	return s
+}
+
+//match returns true if the pattern matches the file path segments or the
+//symbol segments of the caller.
+func (p pattern) match(file, sym fileSegments) bool {
+	if len(p.segs) == 0 {
+		return true
+	}
+	return p.matchSegs(p.segs, file) || (len(sym.segs) > 0 && p.matchSegs(p.sym, sym))
+}
+
+//matchSegs returns true if the pattern segments match the segments.
+func (p pattern) matchSegs(segs []string, f fileSegments) bool {
+	//The range of segments the pattern can start at.
+	first, last := 0, len(f.segs)-len(segs)
+	if p.exact {
+		//The package is the directory so only the file name can follow.
+		last--
+		first = last
+	}
+	if p.anchored {
+		last = 0
+	} else if f.root > 0 && !p.vendor {
+		if first > f.root || last < f.root {
+			return false
+		}
+		first, last = f.root, f.root
+	}
+
+	for i := first; i >= 0 && i <= last; i++ {
+		if matchAt(segs, f.segs[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+//matchAt returns true if the pattern segments match the start of the segments.
+func matchAt(pat, segs []string) bool {
+	for i := range pat {
+		if ok, _ := path.Match(pat[i], segs[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+//fileSegments is a file path split into segments for matching.
+type fileSegments struct {
+	segs []string
+	//root is the index of the first segment of the import path when it is
+	//known, the segment after the last vendor directory or the module cache.
+	root int
+}
+
+//splitFile splits the file path into segments removing any module versions.
+func splitFile(file string) fileSegments {
+	f := fileSegments{}
+
+	for _, s := range strings.Split(strings.Replace(file, `\`, "/", -1), "/") {
+		if s == "" {
+			continue
+		}
+		if i := strings.Index(s, "@"); i > 0 {
+			s = s[:i]
+		}
+		f.segs = append(f.segs, s)
+		if s == "vendor" || (s == "mod" && len(f.segs) > 1 && f.segs[len(f.segs)-2] == "pkg") {
+			f.root = len(f.segs)
+		}
+	}
+
+	return f
+}
+
+//splitSymbol splits the import path and function name of the caller into
+//segments for matching.
+func splitSymbol(l *LogLine) fileSegments {
+	if l.Package == "" {
+		return fileSegments{}
+	}
+
+	f := splitFile(l.Package)
+	f.segs = append(f.segs, l.Function)
+	return f
+}
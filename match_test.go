@@ -0,0 +1,135 @@
+package logfilter_test
+
+import (
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		find  string
+		file  string
+		match bool
+	}{
+		{"acme/db", "/go/src/github.com/acme/db/pool.go", true},
+		{"acme/db", "/go/src/github.com/acme/db/sql/conn.go", true},
+		{"acme/db", "/go/src/github.com/acme/dbutil/util.go", false},
+		{"acme/db", "/go/src/github.com/x/vendor/github.com/acme/db/pool.go", false},
+		{"vendor/github.com/acme/db", "/go/src/github.com/x/vendor/github.com/acme/db/pool.go", true},
+		{"acme/db", "/go/src/github.com/x/vendor/acme/db/pool.go", true},
+		{"github.com/acme/db", "/go/pkg/mod/github.com/acme/db@v1.2.3/pool.go", true},
+		{"acme/db", "/go/pkg/mod/github.com/acme/db@v1.2.3/pool.go", false},
+		{"acme/db/...", "/go/src/github.com/acme/db/sql/conn.go", true},
+		{"acme/db$", "/go/src/github.com/acme/db/pool.go", true},
+		{"acme/db$", "/go/src/github.com/acme/db/sql/conn.go", false},
+		{"acme/*/internal", "/go/src/github.com/acme/db/internal/pool.go", true},
+		{"acme/*/internal", "/go/src/github.com/acme/db/sql/internal/pool.go", false},
+		{"/go/src/github.com", "/go/src/github.com/acme/db/pool.go", true},
+		{"/src/github.com", "/go/src/github.com/acme/db/pool.go", false},
+		{"db/pool.go", "/go/src/github.com/acme/db/pool.go", true},
+	}
+
+	for _, test := range tests {
+		f := logfilter.NewFilter()
+		f.Default(logfilter.Off)
+		f.Include(test.find)
+
+		l := logfilter.LogLine{File: test.file, Level: logfilter.Info}
+		if f.Match(&l) != test.match {
+			t.Errorf("Pattern %s file %s expected %t, actual %t", test.find, test.file, test.match, !test.match)
+		}
+	}
+}
+
+func TestPatternSpecificity(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Exclude("acme/*/internal")
+	f.Include("acme/db/internal").When(logfilter.Info)
+	f.Exclude("acme/db$")
+
+	tests := []struct {
+		file  string
+		match bool
+	}{
+		{"/src/acme/db/internal/pool.go", true},
+		{"/src/acme/web/internal/pool.go", false},
+		{"/src/acme/db/pool.go", false},
+		{"/src/acme/db/sql/conn.go", true},
+	}
+
+	for _, test := range tests {
+		l := logfilter.LogLine{File: test.file, Level: logfilter.Info}
+		if f.Match(&l) != test.match {
+			t.Errorf("File %s expected %t, actual %t", test.file, test.match, !test.match)
+		}
+	}
+}
+
+func TestPatternSymbol(t *testing.T) {
+	tests := []struct {
+		find  string
+		match bool
+	}{
+		{"github.com/acme/db", true},
+		{"github.com/acme/db$", true},
+		{"github.com/acme/db.(*Pool).Acquire", true},
+		{"github.com/acme/db.(*Pool).*", true},
+		{"github.com/acme/db.(*Pool).Release", false},
+		{"github.com/acme/dbutil", false},
+	}
+
+	for _, test := range tests {
+		f := logfilter.NewFilter()
+		f.Default(logfilter.Off)
+		f.Include(test.find)
+
+		//The file path from a -trimpath build doesn't include the import path.
+		l := logfilter.LogLine{
+			File:     "db/pool.go",
+			Package:  "github.com/acme/db",
+			Function: "(*Pool).Acquire",
+			Level:    logfilter.Info,
+		}
+		if f.Match(&l) != test.match {
+			t.Errorf("Pattern %s function %s.%s expected %t, actual %t", test.find, l.Package, l.Function, test.match, !test.match)
+		}
+	}
+}
+
+func TestPatternSymbolLiteral(t *testing.T) {
+	tests := []struct {
+		find     string
+		pkg      string
+		function string
+		match    bool
+	}{
+		{"github.com/acme/db.(*Pool).Acquire", "github.com/acme/db", "(Pool).Acquire", false},
+		{"github.com/acme/db.(*Pool).*", "github.com/acme/db", "(Conn).Acquire", false},
+		{"gopkg.in/yaml.v2", "gopkg.in/yaml.v2", "Marshal", true},
+		{"gopkg.in/yaml.v2$", "gopkg.in/yaml.v2", "Marshal", true},
+		{"gopkg.in/yaml.v2.Marshal", "gopkg.in/yaml.v2", "Marshal", true},
+		{"gopkg.in/yaml.v2.Unmarshal", "gopkg.in/yaml.v2", "Marshal", false},
+	}
+
+	for _, test := range tests {
+		f := logfilter.NewFilter()
+		f.Default(logfilter.Off)
+		f.Include(test.find)
+
+		l := logfilter.LogLine{File: "yaml/encode.go", Package: test.pkg, Function: test.function, Level: logfilter.Info}
+		if f.Match(&l) != test.match {
+			t.Errorf("Pattern %s function %s.%s expected %t, actual %t", test.find, l.Package, l.Function, test.match, !test.match)
+		}
+	}
+
+	//The literal receiver is more specific than a glob of its methods.
+	f := logfilter.NewFilter()
+	f.Exclude("github.com/acme/db.(*Pool).*")
+	f.Include("github.com/acme/db.(*Pool).Acquire").When(logfilter.Info)
+
+	l := logfilter.LogLine{File: "db/pool.go", Package: "github.com/acme/db", Function: "(*Pool).Acquire", Level: logfilter.Info}
+	if !f.Match(&l) {
+		t.Errorf("Pattern %s expected to be more specific than %s", "github.com/acme/db.(*Pool).Acquire", "github.com/acme/db.(*Pool).*")
+	}
+}
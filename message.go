@@ -0,0 +1,112 @@
+package logfilter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//IncludeMessage adds filter object(s) to the standard filter which write out
+//lines containing s. See (*Filter).IncludeMessage.
+func IncludeMessage(s string, packagenames ...string) filters {
+	return stdFilters.IncludeMessage(s, packagenames...)
+}
+
+//ExcludeMessage adds filter object(s) to the standard filter which drop lines
+//containing s. See (*Filter).ExcludeMessage.
+func ExcludeMessage(s string, packagenames ...string) filters {
+	return stdFilters.ExcludeMessage(s, packagenames...)
+}
+
+//IncludeRegexp adds filter object(s) to the standard filter which write out
+//lines matching re. See (*Filter).IncludeRegexp.
+func IncludeRegexp(re *regexp.Regexp, packagenames ...string) filters {
+	return stdFilters.IncludeRegexp(re, packagenames...)
+}
+
+//ExcludeRegexp adds filter object(s) to the standard filter which drop lines
+//matching re. See (*Filter).ExcludeRegexp.
+func ExcludeRegexp(re *regexp.Regexp, packagenames ...string) filters {
+	return stdFilters.ExcludeRegexp(re, packagenames...)
+}
+
+//IncludeMessage adds filter object(s) which write out lines whose message
+//contains s, from the packages named or from all packages if none are. As
+//with Include the level defaults to undefined (i.e. lowest).
+//
+//Message filters sit in the same table as the package filters and are more
+//specific than any filter without a message condition.
+func (f *Filter) IncludeMessage(s string, packagenames ...string) filters {
+	n := newFilter("", true, Undefined)
+	n.contains = s
+	return f.add(n, allPackages(packagenames))
+}
+
+//ExcludeMessage adds filter object(s) which drop lines whose message contains
+//s, from the packages named or from all packages if none are. As with Exclude
+//the level defaults to off (i.e. highest).
+//i.e. ExcludeMessage("connection reset by peer")
+func (f *Filter) ExcludeMessage(s string, packagenames ...string) filters {
+	n := newFilter("", false, Off)
+	n.contains = s
+	return f.add(n, allPackages(packagenames))
+}
+
+//IncludeRegexp adds filter object(s) which write out lines whose message
+//matches re, from the packages named or from all packages if none are.
+func (f *Filter) IncludeRegexp(re *regexp.Regexp, packagenames ...string) filters {
+	n := newFilter("", true, Undefined)
+	n.re = re
+	return f.add(n, allPackages(packagenames))
+}
+
+//ExcludeRegexp adds filter object(s) which drop lines whose message matches
+//re, from the packages named or from all packages if none are.
+func (f *Filter) ExcludeRegexp(re *regexp.Regexp, packagenames ...string) filters {
+	n := newFilter("", false, Off)
+	n.re = re
+	return f.add(n, allPackages(packagenames))
+}
+
+//allPackages returns the package names or the name matching all packages if
+//there are none.
+func allPackages(packagenames []string) []string {
+	if len(packagenames) == 0 {
+		return []string{""}
+	}
+	return packagenames
+}
+
+//message returns the message condition of the filter in the spec format,
+//~"text" for a substring or ~/expression/ for a regular expression.
+func (f filter) message() string {
+	switch {
+	case f.re != nil:
+		return "~/" + strings.Replace(f.re.String(), "/", `\/`, -1) + "/"
+	case f.contains != "":
+		return "~" + strconv.Quote(f.contains)
+	}
+	return ""
+}
+
+//matchMessage returns true if the message meets the message condition of the
+//filter, which is always the case for filters without one.
+func (f filter) matchMessage(m string) bool {
+	switch {
+	case f.re != nil:
+		return f.re.MatchString(m)
+	case f.contains != "":
+		return strings.Contains(m, f.contains)
+	}
+	return true
+}
+
+//specificity ranks the filter against the others, message filters rank above
+//all package filters which are ranked by their pattern.
+func (f filter) specificity() int {
+	s := f.pat.specificity()
+	if f.re != nil || f.contains != "" {
+		s |= 1 << 20
+	}
+	return s
+}
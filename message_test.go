@@ -0,0 +1,72 @@
+package logfilter_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestMessageFilters(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Include("acme").When(logfilter.Trace)
+	f.ExcludeMessage("connection reset by peer")
+	f.Default(logfilter.Off)
+	f.IncludeRegexp(regexp.MustCompile(`tenant=42\b`), "acme/web").When(logfilter.Info)
+
+	tests := []struct {
+		file    string
+		level   logfilter.Level
+		message string
+		out     bool
+	}{
+		{"/src/acme/db/db.go", logfilter.Debug, "read: connection reset by peer", false},
+		{"/src/acme/db/db.go", logfilter.Debug, "slow query", true},
+		{"/src/other/x.go", logfilter.Error, "slow query tenant=42", false},
+		{"/src/acme/web/web.go", logfilter.Info, "login tenant=42", true},
+		{"/src/acme/web/web.go", logfilter.Info, "login tenant=421", true},
+	}
+
+	for _, test := range tests {
+		l := logfilter.LogLine{File: test.file, Level: test.level, Message: test.message}
+		if f.Match(&l) != test.out {
+			t.Errorf("Message %q from %s expected %t, actual %t", test.message, test.file, test.out, !test.out)
+		}
+	}
+
+	e := `off,acme=trace,-~"connection reset by peer",acme/web~/tenant=42\b/=info`
+	if f.String() != e {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\"\n", e, f.String())
+	}
+
+	g := logfilter.NewFilter()
+	if err := g.ApplySpec(e); err != nil || g.String() != e {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\" %v\n", e, g.String(), err)
+	}
+
+	f.Remove(`~"connection reset by peer"`)
+	l := logfilter.LogLine{File: "/src/acme/db/db.go", Level: logfilter.Debug, Message: "connection reset by peer"}
+	if !f.Match(&l) {
+		t.Errorf("Expected message filter to be removed, actual %s", f)
+	}
+}
+
+func TestMessageSpec(t *testing.T) {
+	f := logfilter.NewFilter()
+
+	s := `-~"a, \"b\"",acme~/x\/y,z/=debug`
+	if err := f.ApplySpec(s); err != nil {
+		t.Fatalf("Unexpected error applying spec %q: %v", s, err)
+	}
+
+	l := logfilter.LogLine{File: "/src/acme/db.go", Level: logfilter.Info, Message: `say a, "b"`}
+	if f.Match(&l) {
+		t.Errorf("Message %q expected to be dropped", l.Message)
+	}
+
+	for _, s := range []string{`-~"unterminated`, `-~/[/`, `acme~"x"junk`} {
+		if err := f.ApplySpec(s); err == nil {
+			t.Errorf("Expected error applying spec %q", s)
+		}
+	}
+}
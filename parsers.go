@@ -0,0 +1,245 @@
+package logfilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//jsonLevelKeys are the keys holding the level of a JSON message, in the order
+//they are looked for.
+var jsonLevelKeys = []string{"level", "severity", "lvl"}
+
+//jsonMessageKeys are the keys holding the message of a JSON message.
+var jsonMessageKeys = []string{"msg", "message"}
+
+//JSONParser is a parser for messages which are a JSON object, as written by
+//structured loggers, i.e. {"level":"warn","msg":"disk full","disk":"sda1"}.
+//The level is read from the level, severity or lvl key and the message from
+//the msg or message key. Numeric levels, as written by pino and bunyan (10
+//trace to 60 fatal), are understood as well as names.
+//
+//The remaining keys are returned as the Fields of the line and the message is
+//the msg value alone. The fields are written by LogfmtFormat, not StdFormat.
+func JSONParser(m string) (Level, string, Fields) {
+	s := strings.TrimSpace(m)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return Undefined, m, nil
+	}
+
+	keys, values, ok := jsonObject(s)
+	if !ok {
+		return Undefined, m, nil
+	}
+
+	l := Undefined
+	msg := ""
+	var f Fields
+
+	for i, k := range keys {
+		v := jsonString(values[i])
+		switch {
+		case l == Undefined && containsFold(jsonLevelKeys, k):
+			if l = jsonNumericLevel(values[i]); l == Undefined {
+				l = jsonLevel(v)
+			}
+			if l == Undefined {
+				f = append(f, Field{Key: k, Value: v})
+			}
+		case msg == "" && containsFold(jsonMessageKeys, k):
+			msg = v
+		default:
+			f = append(f, Field{Key: k, Value: v})
+		}
+	}
+
+	if l == Undefined {
+		return Undefined, m, nil
+	}
+	return l, msg + "\n", f
+}
+
+//glogHeader matches the header glog and klog write before the message,
+//Lmmdd hh:mm:ss.uuuuuu threadid file:line]
+var glogHeader = regexp.MustCompile(`^([IWEF])[0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2}\.[0-9]{6} +[0-9]+ [^ \]]+:[0-9]+\] ?`)
+
+//glogLevels maps the letter starting a glog line to the level.
+var glogLevels = map[byte]Level{
+	'I': Info,
+	'W': Warning,
+	'E': Error,
+	'F': Fatal,
+}
+
+//GlogParser is a parser for the glog and klog convention, where the message
+//follows a header starting with the level letter (I, W, E or F):
+//	I0102 15:04:05.123456    123 server.go:10] message
+func GlogParser(m string) (Level, string) {
+	h := glogHeader.FindStringSubmatch(m)
+	if h == nil {
+		return Undefined, m
+	}
+	return glogLevels[h[1][0]], m[len(h[0]):]
+}
+
+//LogrusParser is a parser for the logrus text format, logfmt pairs including
+//level and msg:
+//	time="2006-01-02T15:04:05Z" level=info msg="user login" user=42
+//
+//The time is dropped and the remaining pairs are returned as the Fields of the
+//line, as they are by JSONParser.
+func LogrusParser(m string) (Level, string, Fields) {
+	text, f := splitLogfmt(m)
+	if text != "" {
+		return Undefined, m, nil
+	}
+
+	l := Undefined
+	msg := ""
+	var rest Fields
+
+	for _, fl := range f {
+		switch {
+		case l == Undefined && fl.Key == "level":
+			l = jsonLevel(fl.Value)
+		case msg == "" && fl.Key == "msg":
+			msg = fl.Value
+		case fl.Key != "time":
+			rest = append(rest, fl)
+		}
+	}
+
+	if l == Undefined {
+		return Undefined, m, nil
+	}
+	return l, msg + "\n", rest
+}
+
+//syslogSeverities maps the syslog severities, from emergency (0) to debug (7),
+//to levels.
+var syslogSeverities = [8]Level{Fatal, Fatal, Fatal, Error, Warning, Info, Info, Debug}
+
+//SyslogParser is a parser for messages starting with a syslog priority, as
+//written to /dev/kmsg or read by systemd. The level is the severity of the
+//priority (the priority modulo 8).
+//	<3>message
+func SyslogParser(m string) (Level, string) {
+	if !strings.HasPrefix(m, "<") {
+		return Undefined, m
+	}
+
+	e := strings.Index(m, ">")
+	if e < 2 || e > 4 {
+		return Undefined, m
+	}
+
+	p, err := strconv.Atoi(m[1:e])
+	if err != nil || p < 0 || p > 191 || (e > 2 && m[1] == '0') {
+		return Undefined, m
+	}
+	return syslogSeverities[p%8], strings.TrimLeft(m[e+1:], " ")
+}
+
+//jsonObject returns the keys and raw values of the JSON object in order, ok is
+//false if it isn't a valid object.
+func jsonObject(s string) (keys []string, values []json.RawMessage, ok bool) {
+	d := json.NewDecoder(strings.NewReader(s))
+	if t, err := d.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil, false
+	}
+
+	for d.More() {
+		t, err := d.Token()
+		if err != nil {
+			return nil, nil, false
+		}
+
+		var v json.RawMessage
+		if err := d.Decode(&v); err != nil {
+			return nil, nil, false
+		}
+
+		keys = append(keys, t.(string))
+		values = append(values, v)
+	}
+
+	if t, err := d.Token(); err != nil || t != json.Delim('}') {
+		return nil, nil, false
+	}
+	if _, err := d.Token(); err == nil {
+		//There is something after the object.
+		return nil, nil, false
+	}
+	return keys, values, true
+}
+
+//jsonString returns the value of a JSON string, or the compacted JSON of any
+//other value.
+func jsonString(v json.RawMessage) string {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+
+	var b bytes.Buffer
+	if json.Compact(&b, v) != nil {
+		return string(v)
+	}
+	return b.String()
+}
+
+//jsonLevel converts the level of a JSON message to a Level, the common names
+//used by JSON loggers (i.e. warn) are level aliases. The value is known to be
+//a level so the word aliases (i.e. panic) are understood too.
+func jsonLevel(s string) Level {
+	l := StringToLevel(s)
+	if l == Undefined {
+		l = wordLevelAliases[strings.ToLower(s)]
+	}
+	if l != Off {
+		return l
+	}
+	return Undefined
+}
+
+//jsonNumericLevels are the lowest numbers of the levels used by pino and
+//bunyan, from the highest down.
+var jsonNumericLevels = []struct {
+	n int
+	l Level
+}{
+	{60, Fatal},
+	{50, Error},
+	{40, Warning},
+	{30, Info},
+	{20, Debug},
+	{10, Trace},
+}
+
+//jsonNumericLevel converts a numeric JSON level to a Level, custom levels
+//between the standard numbers take the level below them. It returns Undefined
+//if the value isn't a number or is below trace.
+func jsonNumericLevel(v json.RawMessage) Level {
+	var n float64
+	if json.Unmarshal(v, &n) != nil {
+		return Undefined
+	}
+	for _, nl := range jsonNumericLevels {
+		if n >= float64(nl.n) {
+			return nl.l
+		}
+	}
+	return Undefined
+}
+
+//containsFold returns true if the key is in the list, ignoring case.
+func containsFold(list []string, key string) bool {
+	for _, k := range list {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
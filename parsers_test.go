@@ -0,0 +1,135 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestJSONParser(t *testing.T) {
+	tests := []struct {
+		m       string
+		level   logfilter.Level
+		message string
+		fields  string
+	}{
+		{`{"level":"warn","msg":"disk full"}` + "\n", logfilter.Warning, "disk full\n", ""},
+		{`{"severity":"ERROR","message":"failed","code":500,"tags":["a","b"]}`, logfilter.Error, "failed\n", `code=500 tags="[\"a\",\"b\"]"`},
+		{`{"lvl":"debug","user":"42","msg":"login"}`, logfilter.Debug, "login\n", "user=42"},
+		{`{"level":"info","err":"connection reset"}`, logfilter.Info, "\n", `err="connection reset"`},
+		{`{"level":30,"msg":"listening","port":8080}`, logfilter.Info, "listening\n", "port=8080"},
+		{`{"level":50,"msg":"failed"}`, logfilter.Error, "failed\n", ""},
+		{`{"level":35,"msg":"custom"}`, logfilter.Info, "custom\n", ""},
+		{`{"level":5,"msg":"too low"}`, logfilter.Undefined, `{"level":5,"msg":"too low"}`, ""},
+		{`{"msg":"no level"}`, logfilter.Undefined, `{"msg":"no level"}`, ""},
+		{`{"level":"warn"`, logfilter.Undefined, `{"level":"warn"`, ""},
+		{`{"level":"warn"} trailing`, logfilter.Undefined, `{"level":"warn"} trailing`, ""},
+		{"Warning: not json", logfilter.Undefined, "Warning: not json", ""},
+	}
+
+	for _, test := range tests {
+		l, m, f := logfilter.JSONParser(test.m)
+		if l != test.level || m != test.message || f.String() != test.fields {
+			t.Errorf("JSONParser(%q) expected %s %q %q, actual %s %q %q", test.m, logfilter.LevelToString(test.level), test.message, test.fields, logfilter.LevelToString(l), m, f.String())
+		}
+	}
+}
+
+func TestJSONParserFields(t *testing.T) {
+	var b bytes.Buffer
+
+	//The keys are fields without any field parsers.
+	l := logfilter.New(&b, "", 0)
+	l.SetStructuredParsers([]logfilter.StructuredParser{logfilter.JSONParser})
+	l.SetFilterFunc(logfilter.And(logfilter.LevelAtLeast(logfilter.Warning), logfilter.FieldEquals("disk", "sda1")))
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Print(`{"level":"warn","msg":"disk full","disk":"sda1"}`)
+	lg.Print(`{"level":"debug","msg":"disk checked","disk":"sda1"}`)
+	lg.Print(`{"level":"warn","msg":"disk full","disk":"sdb1"}`)
+	lg.Print("Warning: disk=sda1")
+
+	if b.String() != "Warning: disk full\n" {
+		t.Errorf("JSON Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: disk full\n", b.String())
+	}
+
+	b.Reset()
+	l.SetFormatter(logfilter.LogfmtFormat)
+	lg.Print(`{"level":"warn","msg":"disk full","disk":"sda1"}`)
+
+	if b.String() != "level=warning msg=\"disk full\" disk=sda1\n" {
+		t.Errorf("JSON Mismatch Expected:\"%s\" Actual:\"%s\"\n", "level=warning msg=\"disk full\" disk=sda1\n", b.String())
+	}
+}
+
+func TestGlogParser(t *testing.T) {
+	tests := []struct {
+		m       string
+		level   logfilter.Level
+		message string
+	}{
+		{"I0102 15:04:05.123456    123 server.go:10] started\n", logfilter.Info, "started\n"},
+		{"W1231 23:59:59.000001 7 pkg/cache.go:99] evicting\n", logfilter.Warning, "evicting\n"},
+		{"E0102 15:04:05.123456 1 a.go:1] failed", logfilter.Error, "failed"},
+		{"F0102 15:04:05.123456 1 a.go:1] dying", logfilter.Fatal, "dying"},
+		{"X0102 15:04:05.123456 1 a.go:1] unknown", logfilter.Undefined, "X0102 15:04:05.123456 1 a.go:1] unknown"},
+		{"Info: not glog", logfilter.Undefined, "Info: not glog"},
+	}
+
+	for _, test := range tests {
+		l, m := logfilter.GlogParser(test.m)
+		if l != test.level || m != test.message {
+			t.Errorf("GlogParser(%q) expected %s %q, actual %s %q", test.m, logfilter.LevelToString(test.level), test.message, logfilter.LevelToString(l), m)
+		}
+	}
+}
+
+func TestLogrusParser(t *testing.T) {
+	tests := []struct {
+		m       string
+		level   logfilter.Level
+		message string
+		fields  string
+	}{
+		{`time="2006-01-02T15:04:05Z" level=info msg="user login" user=42` + "\n", logfilter.Info, "user login\n", "user=42"},
+		{`level=warning msg=slow`, logfilter.Warning, "slow\n", ""},
+		{`time="2006-01-02T15:04:05Z" level=panic msg="gone"`, logfilter.Fatal, "gone\n", ""},
+		{`level=info msg="x" and words`, logfilter.Undefined, `level=info msg="x" and words`, ""},
+		{`msg="no level"`, logfilter.Undefined, `msg="no level"`, ""},
+	}
+
+	for _, test := range tests {
+		l, m, f := logfilter.LogrusParser(test.m)
+		if l != test.level || m != test.message || f.String() != test.fields {
+			t.Errorf("LogrusParser(%q) expected %s %q %q, actual %s %q %q", test.m, logfilter.LevelToString(test.level), test.message, test.fields, logfilter.LevelToString(l), m, f.String())
+		}
+	}
+}
+
+func TestSyslogParser(t *testing.T) {
+	tests := []struct {
+		m       string
+		level   logfilter.Level
+		message string
+	}{
+		{"<3>failed\n", logfilter.Error, "failed\n"},
+		{"<4> warning", logfilter.Warning, "warning"},
+		{"<6>info", logfilter.Info, "info"},
+		{"<7>debug", logfilter.Debug, "debug"},
+		{"<0>emergency", logfilter.Fatal, "emergency"},
+		{"<191>local7 debug", logfilter.Debug, "local7 debug"},
+		{"<192>out of range", logfilter.Undefined, "<192>out of range"},
+		{"<03>leading zero", logfilter.Undefined, "<03>leading zero"},
+		{"<x>not a number", logfilter.Undefined, "<x>not a number"},
+		{"no priority", logfilter.Undefined, "no priority"},
+	}
+
+	for _, test := range tests {
+		l, m := logfilter.SyslogParser(test.m)
+		if l != test.level || m != test.message {
+			t.Errorf("SyslogParser(%q) expected %s %q, actual %s %q", test.m, logfilter.LevelToString(test.level), test.message, logfilter.LevelToString(l), m)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package logfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//DefaultSummaryInterval is how often a logger reports the lines suppressed by
+//rate limits, see SetSummaryInterval.
+const DefaultSummaryInterval = 10 * time.Second
+
+//limiter is a token bucket rate limit attached to a filter. A bucket is kept
+//for each call site unless the limit is shared by every line the filter writes.
+type limiter struct {
+	rate   float64 // tokens added per second.
+	burst  float64 // the most tokens a bucket holds.
+	shared bool    // one bucket for the filter rather than one per call site.
+
+	buckets    sync.Map // key string -> *bucket
+	suppressed uint64   // lines dropped since the last summary.
+}
+
+//bucket holds the tokens for a call site, a line is written for each token.
+type bucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+//newLimiter creates a limiter allowing rate lines a second with bursts of up
+//to burst lines.
+func newLimiter(rate float64, burst int, shared bool) *limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		shared: shared,
+	}
+}
+
+//allow takes a token for the line, returning false and counting the line as
+//suppressed if there isn't one.
+func (lm *limiter) allow(l *LogLine) bool {
+	key := ""
+	if !lm.shared {
+		key = l.File + ":" + strconv.Itoa(l.Line)
+	}
+
+	v, ok := lm.buckets.Load(key)
+	if !ok {
+		v, _ = lm.buckets.LoadOrStore(key, &bucket{tokens: lm.burst, last: time.Now()})
+	}
+	b := v.(*bucket)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * lm.rate
+	if b.tokens > lm.burst {
+		b.tokens = lm.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		atomic.AddUint64(&lm.suppressed, 1)
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+//String returns the limit in the spec format, i.e. 10/s:20 or 10/s:20:shared.
+func (lm *limiter) String() string {
+	s := strconv.FormatFloat(lm.rate, 'g', -1, 64) + "/s:" + strconv.FormatFloat(lm.burst, 'g', -1, 64)
+	if lm.shared {
+		s += ":shared"
+	}
+	return s
+}
+
+//parseLimit converts a limit in the spec format (see (*limiter).String) to a
+//limiter, the burst defaults to one line.
+func parseLimit(s string) (*limiter, error) {
+	parts := strings.Split(s, ":")
+
+	shared := false
+	if n := len(parts); n > 1 && strings.EqualFold(parts[n-1], "shared") {
+		shared = true
+		parts = parts[:n-1]
+	}
+	if len(parts) > 2 || !strings.HasSuffix(parts[0], "/s") {
+		return nil, fmt.Errorf("invalid limit %q", s)
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSuffix(parts[0], "/s"), 64)
+	if err != nil || rate < 0 {
+		return nil, fmt.Errorf("invalid limit rate %q", s)
+	}
+
+	burst := 1
+	if len(parts) == 2 {
+		if burst, err = strconv.Atoi(parts[1]); err != nil || burst < 1 {
+			return nil, fmt.Errorf("invalid limit burst %q", s)
+		}
+	}
+	return newLimiter(rate, burst, shared), nil
+}
+
+//Limit rate limits the lines written out by the filters provided to rate lines
+//a second from each call site, with bursts of up to burst lines. Lines over the
+//limit are dropped and counted in the summary written by the logger.
+//i.e. Include("pkg").When(logfilter.Warning).Limit(10, 20)
+func (f filters) Limit(rate float64, burst int) filters {
+	return f.limit(rate, burst, false)
+}
+
+//LimitPackage rate limits the lines written out by the filters provided as
+//Limit does, but with a single limit shared by all the call sites they match.
+func (f filters) LimitPackage(rate float64, burst int) filters {
+	return f.limit(rate, burst, true)
+}
+
+//limit attaches a new limiter to each of the filters.
+func (f filters) limit(rate float64, burst int, shared bool) filters {
+	f.set.update(func(r rules) rules {
+		for _, k := range f.keys {
+			if i := r.find(k); i >= 0 {
+				r[i].limit = newLimiter(rate, burst, shared)
+			}
+		}
+		return r
+	})
+	return f
+}
+
+//Suppressed returns the number of lines dropped by each rate limited rule of
+//the filter, keyed by the rule in the spec format without its sample and limit,
+//since it was last called.
+func (f *Filter) Suppressed() map[string]uint64 {
+	s := map[string]uint64{}
+	for _, r := range f.load() {
+		if r.limit == nil {
+			continue
+		}
+		if n := atomic.SwapUint64(&r.limit.suppressed, 0); n > 0 {
+			s[r.rule()] += n
+		}
+	}
+	return s
+}
+
+//SetSummaryInterval sets how often the standard logger reports the lines
+//suppressed by rate limits.
+func SetSummaryInterval(d time.Duration) {
+	std.SetSummaryInterval(d)
+}
+
+//SetSummaryInterval sets how often the logger reports the lines suppressed by
+//rate limits. The report is written as a Warning line for each rule once the
+//interval has passed, by the next call to Write or by a timer if the logger
+//isn't written to. Zero turns it off.
+func (l *Logger) SetSummaryInterval(d time.Duration) {
+	l.update(func(c *config) { c.summary = d })
+}
+
+//summarise writes the lines suppressed by rate limits if the summary interval
+//has passed, otherwise it starts a timer to write them when it has in case the
+//logger isn't written to again.
+func (l *Logger) summarise(c *config) {
+	if c.summary <= 0 || c.filters == nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&l.summarised)
+	if wait := last + int64(c.summary) - now; wait > 0 {
+		if atomic.CompareAndSwapInt32(&l.summaryTimer, 0, 1) {
+			time.AfterFunc(time.Duration(wait), l.summaryDue)
+		}
+		return
+	}
+	if !atomic.CompareAndSwapInt64(&l.summarised, last, now) {
+		return
+	}
+
+	for r, n := range c.filters.Suppressed() {
+		l.noticeTo(c, Warning, "logfilter: rate limit of rule %s suppressed %s", strconv.Quote(r), plural(n, "line"))
+	}
+}
+
+//summaryDue writes the summary once the interval summarise was waiting for has
+//passed.
+func (l *Logger) summaryDue() {
+	atomic.StoreInt32(&l.summaryTimer, 0)
+
+	l.amu.RLock()
+	defer l.amu.RUnlock()
+
+	l.summarise(l.config())
+}
+
+//plural returns the count followed by the noun, pluralised if required.
+func plural(n uint64, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
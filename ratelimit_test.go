@@ -0,0 +1,84 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestLimit(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetSummaryInterval(0)
+	l.Include("github.com/d2g/logfilter").When(logfilter.Warning).Limit(0, 2)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	for i := 0; i < 5; i++ {
+		lg.Println("Warning: Message")
+	}
+	lg.Println("Error: Other call site")
+
+	if b.String() != "Warning: Message\nWarning: Message\nError: Other call site\n" {
+		t.Errorf("Limit Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: Message\nWarning: Message\nError: Other call site\n", b.String())
+	}
+
+	//The suppressed lines are reported once the interval has passed.
+	b.Reset()
+	l.SetSummaryInterval(time.Nanosecond)
+	lg.Println("Debug: Message")
+
+	if !strings.HasPrefix(b.String(), `Warning: logfilter: rate limit of rule "github.com/d2g/logfilter=warning" suppressed 3 lines`) {
+		t.Errorf("Summary Mismatch Actual:\"%s\"\n", b.String())
+	}
+
+	if s := l.Filters().Suppressed(); len(s) != 0 {
+		t.Errorf("Suppressed expected to be reset, actual %v", s)
+	}
+}
+
+func TestLimitPackage(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetSummaryInterval(0)
+	l.Include("github.com/d2g/logfilter").LimitPackage(0, 1)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println("Info: First")
+	lg.Println("Info: Second")
+
+	if b.String() != "Info: First\n" {
+		t.Errorf("Limit Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Info: First\n", b.String())
+	}
+
+	if s := l.Filters().Suppressed(); s["github.com/d2g/logfilter"] != 1 {
+		t.Errorf("Suppressed expected %d, actual %v", 1, s)
+	}
+}
+
+func TestLimitSummaryTimer(t *testing.T) {
+	var b syncBuffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetSummaryInterval(50 * time.Millisecond)
+	l.Include("github.com/d2g/logfilter").When(logfilter.Warning).Limit(0, 1)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	for i := 0; i < 3; i++ {
+		lg.Println("Warning: Message")
+	}
+
+	//The logger isn't written to again, the summary is written by a timer.
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(b.String(), "suppressed 2 lines") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(b.String(), `Warning: logfilter: rate limit of rule "github.com/d2g/logfilter=warning" suppressed 2 lines`) {
+		t.Errorf("Summary Mismatch Actual:\"%s\"\n", b.String())
+	}
+}
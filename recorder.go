@@ -0,0 +1,108 @@
+package logfilter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//recorder is a ring buffer of the most recent lines filtered out by a logger.
+type recorder struct {
+	mu    sync.Mutex
+	lines []recorded // the ring, its length is the size of the recorder.
+	next  int        // where the next line is recorded.
+	n     int        // the number of lines recorded.
+}
+
+//recorded is a line in the recorder and when it was recorded. The age is
+//taken from when it was recorded as the line's own timestamp is parsed from
+//the log output, which may be in local time or missing.
+type recorded struct {
+	line LogLine
+	at   time.Time
+}
+
+//SetFlightRecorder sets the size and age of the standard logger's flight
+//recorder.
+func SetFlightRecorder(size int, age time.Duration) {
+	std.SetFlightRecorder(size, age)
+}
+
+//SetFlightRecorder makes the logger keep the last size lines its filter rules
+//filter out in memory. When an Error or Fatal line is written the recorded
+//lines no older than age (zero for any age) are written before it between
+//marker lines, so the context of a failure is kept without writing it all out
+//all the time. Lines dropped by a sample, a rate limit or a disabled call site
+//aren't kept. A size of zero turns it off.
+func (l *Logger) SetFlightRecorder(size int, age time.Duration) {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+
+	l.update(func(c *config) { c.recordAge = age })
+	l.rec.lines = nil
+	if size > 0 {
+		l.rec.lines = make([]recorded, size)
+	}
+	l.rec.next, l.rec.n = 0, 0
+}
+
+//record keeps the filtered out line, replacing the oldest if it is full.
+func (r *recorder) record(ll *LogLine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.lines) == 0 {
+		return
+	}
+
+	r.lines[r.next] = recorded{line: *ll, at: time.Now()}
+	r.next = (r.next + 1) % len(r.lines)
+	if r.n < len(r.lines) {
+		r.n++
+	}
+}
+
+//drain returns the recorded lines no older than age, oldest first, and empties
+//the recorder.
+func (r *recorder) drain(age time.Duration) []LogLine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lines []LogLine
+	now := time.Now()
+	for i := 0; i < r.n; i++ {
+		rl := r.lines[(r.next-r.n+i+len(r.lines))%len(r.lines)]
+		if age > 0 && now.Sub(rl.at) > age {
+			continue
+		}
+		lines = append(lines, rl.line)
+	}
+
+	r.n = 0
+	return lines
+}
+
+//dumpRecorder writes the recorded lines before an Error or Fatal line. The
+//caller must hold the write lock.
+func (l *Logger) dumpRecorder(c *config, ll *LogLine) {
+	if ll.Level != Error && ll.Level != Fatal {
+		return
+	}
+
+	lines := l.rec.drain(c.recordAge)
+	if len(lines) == 0 {
+		return
+	}
+
+	m := *ll
+	m.Timestamp = time.Now()
+	m.Message = fmt.Sprintf("logfilter: begin %d lines filtered out before this %s\n", len(lines), LevelToString(ll.Level))
+	c.output.Write(c.formatter(c.prefix, &m, c.flag))
+
+	for i := range lines {
+		c.output.Write(c.formatter(c.prefix, &lines[i], c.flag))
+	}
+
+	m.Message = "logfilter: end of filtered out lines\n"
+	c.output.Write(c.formatter(c.prefix, &m, c.flag))
+}
@@ -0,0 +1,116 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.Default(logfilter.Warning)
+	l.SetFlightRecorder(2, time.Hour)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println("Debug: First")
+	lg.Println("Debug: Second")
+	lg.Println("Info: Third")
+	lg.Println("Warning: Not an error")
+
+	if b.String() != "Warning: Not an error\n" {
+		t.Errorf("Warning Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Warning: Not an error\n", b.String())
+	}
+
+	b.Reset()
+	lg.Println("Error: Failed")
+
+	expected := "Error: logfilter: begin 2 lines filtered out before this Error\n" +
+		"Debug: Second\n" +
+		"Info: Third\n" +
+		"Error: logfilter: end of filtered out lines\n" +
+		"Error: Failed\n"
+	if b.String() != expected {
+		t.Errorf("Error Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+
+	//The recorder is emptied once written.
+	b.Reset()
+	lg.Println("Error: Failed again")
+
+	if b.String() != "Error: Failed again\n" {
+		t.Errorf("Empty Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Error: Failed again\n", b.String())
+	}
+}
+
+func TestFlightRecorderAge(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.Default(logfilter.Warning)
+	l.SetFlightRecorder(10, 50*time.Millisecond)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println("Debug: Old")
+	time.Sleep(100 * time.Millisecond)
+	lg.Println("Debug: New")
+	lg.Println("Fatal: Failed")
+
+	expected := "Fatal: logfilter: begin 1 lines filtered out before this Fatal\n" +
+		"Debug: New\n" +
+		"Fatal: logfilter: end of filtered out lines\n" +
+		"Fatal: Failed\n"
+	if b.String() != expected {
+		t.Errorf("Age Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+}
+
+func TestFlightRecorderOnlyFiltered(t *testing.T) {
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	l.SetSummaryInterval(0)
+	l.Default(logfilter.Info)
+	l.Include("github.com/d2g/logfilter").When(logfilter.Warning).Limit(0, 1)
+	l.Filters().SampleLevel(logfilter.Info, 1000000, logfilter.SampleMessage)
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	//The call site is logged once before the recorder is on to find it.
+	_, _, line, _ := runtime.Caller(0)
+	disabled := func() {
+		lg.Println("Debug: Disabled")
+	}
+	disabled()
+	for _, cs := range logfilter.CallSites() {
+		if strings.HasSuffix(cs.File, "recorder_test.go") && cs.Line == line+2 {
+			logfilter.DisableCallSite(cs.File, cs.Line)
+			defer logfilter.ResetCallSite(cs.File, cs.Line)
+		}
+	}
+	l.SetFlightRecorder(10, time.Hour)
+
+	//Lines dropped by a rate limit, a sample or a disabled call site aren't
+	//recorded, only the lines the rules filter out.
+	for i := 0; i < 3; i++ {
+		lg.Println("Warning: Limited")
+	}
+	lg.Println("Info: Sampled")
+	disabled()
+	lg.Println("Debug: Filtered")
+	lg.Println("Error: Failed")
+
+	expected := "Warning: Limited\n" +
+		"Error: logfilter: begin 1 lines filtered out before this Error\n" +
+		"Debug: Filtered\n" +
+		"Error: logfilter: end of filtered out lines\n" +
+		"Error: Failed\n"
+	if b.String() != expected {
+		t.Errorf("Recorded Mismatch Expected:\"%s\" Actual:\"%s\"\n", expected, b.String())
+	}
+}
@@ -0,0 +1,145 @@
+package logfilter
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// levelRegistry holds the levels added with RegisterLevel. A published registry
+// is never modified, changes are made to a copy which then replaces it.
+type levelRegistry struct {
+	names   map[Level]string // the name of each level.
+	levels  map[string]Level // the level of each lower case name.
+	aliases map[string]Level // the aliases the levels were registered with.
+
+	order []Level       // every level from Undefined to Off, in order.
+	ranks map[Level]int // the position of each level in order.
+}
+
+// registry is the current *levelRegistry, changes are serialised by aliasMu.
+var registry atomic.Value
+
+// registeredLevels returns the current registry, which is empty until a level
+// is registered.
+func registeredLevels() *levelRegistry {
+	if r, ok := registry.Load().(*levelRegistry); ok {
+		return r
+	}
+	return &levelRegistry{}
+}
+
+// rank returns the position of the level in the order of the levels, which is
+// its value unless levels have been registered.
+func (r *levelRegistry) rank(l Level) int {
+	if r.ranks == nil {
+		return int(l)
+	}
+	if n, ok := r.ranks[l]; ok {
+		return n
+	}
+	//Unknown levels are ordered by value, above Off.
+	return len(r.order) + int(l)
+}
+
+// rank returns the position of the level in the order of the levels.
+func rank(l Level) int {
+	return registeredLevels().rank(l)
+}
+
+// standardLevels are the levels which can be written, in order.
+var standardLevels = []Level{Trace, Debug, Info, Warning, Error, Fatal}
+
+// RegisterLevel adds a level with the name, ordered directly above the level
+// after (and any levels already registered above it), and returns it. Once
+// registered StringToLevel, LevelToString and so the parsers, formatters and
+// filter rules all understand it. The other names are added to the alias table
+// (see SetLevelAlias). i.e. for a level between Info and Warning:
+//
+//	Notice, err := logfilter.RegisterLevel("Notice", logfilter.Info, "ntc")
+//
+// The value of a registered level is above Off and only identifies it, so
+// compare levels with LevelAtLeast and LevelAtMost and walk them with Levels
+// rather than by value. An error is returned if after isn't a level below Off
+// or the name is already used by a level.
+func RegisterLevel(name string, after Level, names ...string) (Level, error) {
+	aliasMu.Lock()
+	defer aliasMu.Unlock()
+
+	r := registeredLevels()
+	lower := strings.ToLower(name)
+
+	switch {
+	case after < Undefined || after == Off || after > Off && r.names[after] == "":
+		return Undefined, fmt.Errorf("logfilter: level %d for %q to follow isn't a level below Off", after, name)
+	case name == "" || strings.ContainsAny(name, " :[]=,.~"):
+		return Undefined, fmt.Errorf("logfilter: invalid level name %q", name)
+	case lower == "undefined" || isStandardName(lower):
+		return Undefined, fmt.Errorf("logfilter: level %s already exists", name)
+	}
+	if _, ok := r.levels[lower]; ok {
+		return Undefined, fmt.Errorf("logfilter: level %s already exists", name)
+	}
+
+	l := Off + 1 + Level(len(r.names))
+	n := &levelRegistry{
+		names:   map[Level]string{l: name},
+		levels:  map[string]Level{lower: l},
+		aliases: map[string]Level{},
+		ranks:   map[Level]int{},
+	}
+	for k, v := range r.names {
+		n.names[k] = v
+	}
+	for k, v := range r.levels {
+		n.levels[k] = v
+	}
+	for k, v := range r.aliases {
+		n.aliases[k] = v
+	}
+
+	//The level goes after the level and those registered above it, before the
+	//next standard level.
+	order := r.order
+	if order == nil {
+		order = append(append([]Level{Undefined}, standardLevels...), Off)
+	}
+	i := r.rank(after) + 1
+	for i < len(order) && order[i] > Off {
+		i++
+	}
+	n.order = append(append(append([]Level(nil), order[:i]...), l), order[i:]...)
+	for i, lvl := range n.order {
+		n.ranks[lvl] = i
+	}
+
+	a := copyAliases(currentAliases())
+	for _, alias := range names {
+		n.aliases[strings.ToLower(alias)] = l
+		a[strings.ToLower(alias)] = l
+	}
+
+	registry.Store(n)
+	aliases.Store(a)
+	return l, nil
+}
+
+// isStandardName returns true if the lower case name is a standard level.
+func isStandardName(name string) bool {
+	for _, l := range append(standardLevels, Off) {
+		if strings.ToLower(LevelToString(l)) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Levels returns the levels a line can have, the standard levels and those
+// registered with RegisterLevel, from lowest to highest.
+func Levels() []Level {
+	r := registeredLevels()
+	if r.order == nil {
+		return append([]Level(nil), standardLevels...)
+	}
+	return append([]Level(nil), r.order[1:len(r.order)-1]...)
+}
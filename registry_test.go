@@ -0,0 +1,133 @@
+package logfilter_test
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+//The levels registered by registerLevels.
+var notice, critical, audit logfilter.Level
+
+//registerLevels adds the levels used by the tests, which are removed again by
+//logfilter.ResetLevels.
+func registerLevels(t *testing.T) {
+	for _, r := range []struct {
+		l       *logfilter.Level
+		name    string
+		after   logfilter.Level
+		aliases []string
+	}{
+		{&notice, "Notice", logfilter.Info, []string{"ntc"}},
+		{&critical, "Critical", logfilter.Error, nil},
+		{&audit, "Audit", logfilter.Fatal, []string{"aud"}},
+	} {
+		l, err := logfilter.RegisterLevel(r.name, r.after, r.aliases...)
+		if err != nil {
+			t.Fatalf("Unexpected error registering %s: %v", r.name, err)
+		}
+		*r.l = l
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	registerLevels(t)
+	defer logfilter.ResetLevels()
+
+	if l := logfilter.StringToLevel("NOTICE"); l != notice {
+		t.Errorf("StringToLevel(%q) expected %d, actual %d", "NOTICE", notice, l)
+	}
+	if l := logfilter.StringToLevel("ntc"); l != notice {
+		t.Errorf("StringToLevel(%q) expected %d, actual %d", "ntc", notice, l)
+	}
+	if s := logfilter.LevelToString(critical); s != "Critical" {
+		t.Errorf("LevelToString(%d) expected %q, actual %q", critical, "Critical", s)
+	}
+
+	expected := []logfilter.Level{logfilter.Trace, logfilter.Debug, logfilter.Info, notice, logfilter.Warning, logfilter.Error, critical, logfilter.Fatal, audit}
+	if lvls := logfilter.Levels(); !reflect.DeepEqual(lvls, expected) {
+		t.Errorf("Levels expected %v, actual %v", expected, lvls)
+	}
+
+	//The standard levels keep their values.
+	if logfilter.Info != 3 || logfilter.Off != 7 {
+		t.Errorf("Standard levels expected Info %d and Off %d, actual %d and %d", 3, 7, logfilter.Info, logfilter.Off)
+	}
+
+	//A level registered after another registered level goes above it.
+	loud, err := logfilter.RegisterLevel("Loud", logfilter.Info)
+	if err != nil {
+		t.Fatalf("Unexpected error registering %s: %v", "Loud", err)
+	}
+	if lvls := logfilter.Levels(); lvls[3] != notice || lvls[4] != loud {
+		t.Errorf("Levels expected %s then %s after Info, actual %v", "Notice", "Loud", lvls)
+	}
+
+	if !logfilter.LevelAtLeast(logfilter.Info)(&logfilter.LogLine{Level: notice}) || logfilter.LevelAtLeast(logfilter.Warning)(&logfilter.LogLine{Level: notice}) {
+		t.Errorf("LevelAtLeast expected %s between %s and %s", "Notice", "Info", "Warning")
+	}
+
+	for _, r := range []struct {
+		name  string
+		after logfilter.Level
+	}{
+		{"Other", logfilter.Off},
+		{"Other", logfilter.Level(-1)},
+		{"Other", logfilter.Level(100)},
+		{"notice", logfilter.Info},
+		{"warning", logfilter.Info},
+		{"two words", logfilter.Info},
+	} {
+		if _, err := logfilter.RegisterLevel(r.name, r.after); err == nil {
+			t.Errorf("RegisterLevel(%q, %d) expected error", r.name, r.after)
+		}
+	}
+}
+
+func TestRegisteredLevelFiltering(t *testing.T) {
+	registerLevels(t)
+	defer logfilter.ResetLevels()
+
+	var b bytes.Buffer
+
+	l := logfilter.New(&b, "", 0)
+	if err := l.Filters().ApplySpec("notice"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	l.SetParsers([]logfilter.Parser{logfilter.SqrParser, logfilter.StdParser})
+	lg := log.New(l, "", log.LstdFlags|log.Llongfile)
+
+	lg.Println("Info: Hidden")
+	lg.Println("Notice: Shown")
+	lg.Println("[AUD] Shown")
+
+	if b.String() != "Notice: Shown\nAudit: Shown\n" {
+		t.Errorf("Mismatch Expected:\"%s\" Actual:\"%s\"\n", "Notice: Shown\nAudit: Shown\n", b.String())
+	}
+
+	if s := l.Filters().String(); s != "notice" {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\"\n", "notice", s)
+	}
+
+	if lvl := l.Filters().EffectiveLevel("/src/acme/db/pool.go"); lvl != notice {
+		t.Errorf("EffectiveLevel expected %s, actual %s", logfilter.LevelToString(notice), logfilter.LevelToString(lvl))
+	}
+}
+
+func TestResetLevels(t *testing.T) {
+	registerLevels(t)
+	logfilter.ResetLevels()
+
+	if l := logfilter.StringToLevel("notice"); l != logfilter.Undefined {
+		t.Errorf("StringToLevel(%q) after reset expected %s, actual %s", "notice", logfilter.LevelToString(logfilter.Undefined), logfilter.LevelToString(l))
+	}
+	if lvls := logfilter.Levels(); len(lvls) != 6 {
+		t.Errorf("Levels after reset expected %d, actual %v", 6, lvls)
+	}
+	if s := logfilter.LevelToString(audit); s != "Undefined" {
+		t.Errorf("LevelToString(%d) after reset expected %q, actual %q", audit, "Undefined", s)
+	}
+}
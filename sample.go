@@ -0,0 +1,224 @@
+package logfilter
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+//SampleBy is how a sample of lines is chosen.
+type SampleBy int
+
+const (
+	//SampleRandom keeps each line at random.
+	SampleRandom SampleBy = iota
+	//SampleCallSite keeps every line from a sample of the call sites, chosen
+	//by a hash of the file and line.
+	SampleCallSite
+	//SampleMessage keeps every line with a sample of the messages, chosen by a
+	//hash of the message.
+	SampleMessage
+)
+
+//String returns the name of the sampling method.
+func (s SampleBy) String() string {
+	switch s {
+	case SampleCallSite:
+		return "callsite"
+	case SampleMessage:
+		return "message"
+	}
+	return "random"
+}
+
+//sampler keeps a proportion of the lines a filter writes out.
+type sampler struct {
+	n       int     // keep 1 in n lines, zero when set as a percentage.
+	percent float64 // the percentage of lines kept.
+	by      SampleBy
+}
+
+//newSampler returns a sampler keeping 1 in n lines, or nil if that is every line.
+func newSampler(n int, by SampleBy) *sampler {
+	if n <= 1 {
+		return nil
+	}
+	return &sampler{n: n, percent: 100 / float64(n), by: by}
+}
+
+//newPercentSampler returns a sampler keeping the percentage of lines, or nil if
+//that is every line.
+func newPercentSampler(p float64, by SampleBy) *sampler {
+	switch {
+	case p >= 100:
+		return nil
+	case p < 0:
+		p = 0
+	}
+	return &sampler{percent: p, by: by}
+}
+
+//keep returns true if the line is in the sample.
+func (s *sampler) keep(l *LogLine) bool {
+	var x float64
+
+	switch s.by {
+	case SampleCallSite:
+		x = hashSample(l.File + ":" + strconv.Itoa(l.Line))
+	case SampleMessage:
+		x = hashSample(l.Message)
+	default:
+		x = rand.Float64()
+	}
+	return x*100 < s.percent
+}
+
+//hashSample maps the string to a number in [0,1) so the same string is always
+//in or out of a sample.
+func hashSample(s string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return float64(h.Sum32()) / (1 << 32)
+}
+
+//String returns the sample in the spec format, 1/n or a percentage followed by
+//the method if it isn't random, i.e. 1/100 or 5%:callsite.
+func (s *sampler) String() string {
+	r := strconv.FormatFloat(s.percent, 'g', -1, 64) + "%"
+	if s.n > 0 {
+		r = "1/" + strconv.Itoa(s.n)
+	}
+	if s.by != SampleRandom {
+		r += ":" + s.by.String()
+	}
+	return r
+}
+
+//parseSample converts a sample in the spec format (see (*sampler).String) to
+//a sampler, nil for an empty string or if every line is kept.
+func parseSample(s string) (*sampler, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	by := SampleRandom
+	if i := strings.Index(s, ":"); i >= 0 {
+		switch strings.ToLower(s[i+1:]) {
+		case "random":
+		case "callsite":
+			by = SampleCallSite
+		case "message":
+			by = SampleMessage
+		default:
+			return nil, fmt.Errorf("unknown sample method %q", s[i+1:])
+		}
+		s = s[:i]
+	}
+
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("invalid sample percentage %q", s)
+		}
+		return newPercentSampler(p, by), nil
+	}
+
+	if !strings.HasPrefix(s, "1/") {
+		return nil, fmt.Errorf("invalid sample %q", s)
+	}
+	n, err := strconv.Atoi(s[2:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid sample %q", s)
+	}
+	return newSampler(n, by), nil
+}
+
+//Sample keeps 1 in n of the lines written out by the filters provided, chosen
+//as by says. The rest are dropped.
+//i.e. Include("pkg").When(logfilter.Trace).Sample(100, logfilter.SampleRandom)
+func (f filters) Sample(n int, by SampleBy) filters {
+	return f.sample(newSampler(n, by))
+}
+
+//SamplePercent keeps the percentage of the lines written out by the filters
+//provided, chosen as by says. The rest are dropped.
+func (f filters) SamplePercent(p float64, by SampleBy) filters {
+	return f.sample(newPercentSampler(p, by))
+}
+
+//sample sets the sampler of each of the filters.
+func (f filters) sample(s *sampler) filters {
+	f.set.update(func(r rules) rules {
+		for _, k := range f.keys {
+			if i := r.find(k); i >= 0 {
+				r[i].sample = s
+			}
+		}
+		return r
+	})
+	return f
+}
+
+//levelSamplers are the samplers applied to each level by a Filter. A published
+//map is never modified.
+type levelSamplers map[Level]*sampler
+
+//SampleLevel keeps 1 in n of the lines of the level written out by the
+//standard filter.
+func SampleLevel(lvl Level, n int, by SampleBy) {
+	stdFilters.SampleLevel(lvl, n, by)
+}
+
+//SampleLevelPercent keeps the percentage of the lines of the level written out
+//by the standard filter.
+func SampleLevelPercent(lvl Level, p float64, by SampleBy) {
+	stdFilters.SampleLevelPercent(lvl, p, by)
+}
+
+//SampleLevel keeps 1 in n of the lines of the level written out by the filter,
+//whichever rule decided to write them, chosen as by says. A n of 1 or less
+//keeps every line.
+func (f *Filter) SampleLevel(lvl Level, n int, by SampleBy) {
+	f.setLevelSampler(lvl, newSampler(n, by))
+}
+
+//SampleLevelPercent keeps the percentage of the lines of the level written out
+//by the filter, chosen as by says.
+func (f *Filter) SampleLevelPercent(lvl Level, p float64, by SampleBy) {
+	f.setLevelSampler(lvl, newPercentSampler(p, by))
+}
+
+//setLevelSamplers replaces all of the level samplers.
+func (f *Filter) setLevelSamplers(s levelSamplers) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.s.Store(s)
+}
+
+//setLevelSampler publishes a copy of the level samplers with the sampler for
+//the level replaced, or removed if s is nil.
+func (f *Filter) setLevelSampler(lvl Level, s *sampler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := levelSamplers{}
+	for l, ls := range f.levelSamplers() {
+		n[l] = ls
+	}
+
+	if s != nil {
+		n[lvl] = s
+	} else {
+		delete(n, lvl)
+	}
+	f.s.Store(n)
+}
+
+//levelSamplers returns the current level samplers.
+func (f *Filter) levelSamplers() levelSamplers {
+	s, _ := f.s.Load().(levelSamplers)
+	return s
+}
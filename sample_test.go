@@ -0,0 +1,96 @@
+package logfilter_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestFiltersSample(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Include("acme/db").When(logfilter.Trace).Sample(10, logfilter.SampleRandom)
+
+	written := 0
+	for i := 0; i < 10000; i++ {
+		if f.Match(&logfilter.LogLine{File: "/src/acme/db/pool.go", Line: i, Level: logfilter.Trace}) {
+			written++
+		}
+	}
+
+	if written < 700 || written > 1300 {
+		t.Errorf("Sample of 1 in %d expected about %d lines, actual %d", 10, 1000, written)
+	}
+
+	//Other packages aren't sampled.
+	if !f.Match(&logfilter.LogLine{File: "/src/acme/web/server.go", Level: logfilter.Trace}) {
+		t.Errorf("Expected line from outside the sampled package to be written")
+	}
+}
+
+func TestSampleDeterministic(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Include("acme/db").SamplePercent(50, logfilter.SampleCallSite)
+
+	//A call site is always in or out of the sample.
+	in := 0
+	for i := 0; i < 1000; i++ {
+		l := logfilter.LogLine{File: "/src/acme/db/pool.go", Line: i, Level: logfilter.Debug}
+		m := f.Match(&l)
+		for j := 0; j < 3; j++ {
+			if f.Match(&l) != m {
+				t.Fatalf("Call site %s:%d sampled inconsistently", l.File, l.Line)
+			}
+		}
+		if m {
+			in++
+		}
+	}
+
+	if in < 400 || in > 600 {
+		t.Errorf("Sample of %d%% expected about %d call sites, actual %d", 50, 500, in)
+	}
+}
+
+func TestSampleLevel(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.SampleLevel(logfilter.Debug, 4, logfilter.SampleMessage)
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		l := logfilter.LogLine{File: "/src/acme/db/pool.go", Level: logfilter.Debug, Message: "query " + strconv.Itoa(i)}
+		if f.Match(&l) {
+			kept++
+		}
+
+		l.Level = logfilter.Info
+		if !f.Match(&l) {
+			t.Fatalf("Expected %s line to be written", logfilter.LevelToString(l.Level))
+		}
+	}
+
+	if kept < 175 || kept > 325 {
+		t.Errorf("Sample of 1 in %d expected about %d lines, actual %d", 4, 250, kept)
+	}
+
+	//Reset removes the level samples.
+	f.Reset()
+	for i := 0; i < 100; i++ {
+		if !f.Match(&logfilter.LogLine{Level: logfilter.Debug, Message: strconv.Itoa(i)}) {
+			t.Fatalf("Expected line to be written after Reset")
+		}
+	}
+}
+
+func TestSampleLevelEffectiveLevel(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Debug)
+	f.SampleLevel(logfilter.Debug, 2, logfilter.SampleRandom)
+
+	//The level is reported before sampling, so it is the same every time.
+	for i := 0; i < 100; i++ {
+		if lvl := f.EffectiveLevel("/src/acme/db/pool.go"); lvl != logfilter.Debug {
+			t.Fatalf("EffectiveLevel expected %s, actual %s", logfilter.LevelToString(logfilter.Debug), logfilter.LevelToString(lvl))
+		}
+	}
+}
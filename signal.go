@@ -0,0 +1,60 @@
+package logfilter
+
+import (
+	"errors"
+	"os"
+	"sort"
+)
+
+//DefaultLevel returns the level set by Default on the filter.
+func (f *Filter) DefaultLevel() Level {
+	r := f.load()
+	if i := r.find(""); i >= 0 {
+		return r[i].min
+	}
+	return Undefined
+}
+
+//step moves the default level of the logger's filter by n levels (see
+//Levels), within Undefined to Off, and returns the new level.
+func (l *Logger) step(n int) Level {
+	f := l.Filters()
+	lvls := append(append([]Level{Undefined}, Levels()...), Off)
+
+	d := rank(f.DefaultLevel())
+	i := sort.Search(len(lvls), func(i int) bool { return rank(lvls[i]) >= d })
+	i += n
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(lvls):
+		i = len(lvls) - 1
+	}
+
+	f.Default(lvls[i])
+	return lvls[i]
+}
+
+//errNoReload is returned by reload when there is nothing to reload from.
+var errNoReload = errors.New("logfilter: no config file or " + SpecEnv + " spec to reload")
+
+//reload reapplies the config file, or if there isn't one the LOGFILTER spec.
+//If there is neither the rules are left alone and an error is returned,
+//rather than replacing them with the defaults.
+func (l *Logger) reload(path string) error {
+	if path != "" {
+		return l.LoadConfig(path)
+	}
+
+	spec := os.Getenv(SpecEnv)
+	if spec == "" {
+		return errNoReload
+	}
+	return l.Filters().ApplySpec(spec)
+}
+
+//noticeFilter reports the effective configuration of the filter.
+func (l *Logger) noticeFilter(reason string) {
+	f := l.Filters()
+	l.notice(Info, "logfilter: %s, default level %s, rules %q", reason, LevelToString(f.DefaultLevel()), f.String())
+}
@@ -0,0 +1,20 @@
+//go:build windows || plan9 || js || wasip1
+// +build windows plan9 js wasip1
+
+package logfilter
+
+import (
+	"errors"
+	"runtime"
+)
+
+//HandleSignals controls the standard logger with signals.
+//See (*Logger).HandleSignals.
+func HandleSignals(config string) (func(), error) {
+	return std.HandleSignals(config)
+}
+
+//HandleSignals isn't supported on this platform and returns an error.
+func (l *Logger) HandleSignals(config string) (func(), error) {
+	return nil, errors.New("logfilter: signals aren't supported on " + runtime.GOOS)
+}
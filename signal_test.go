@@ -0,0 +1,111 @@
+//go:build !windows && !plan9 && !js && !wasip1
+// +build !windows,!plan9,!js,!wasip1
+
+package logfilter_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestHandleSignals(t *testing.T) {
+	var b syncBuffer
+
+	l := logfilter.New(&b, "", 0)
+	l.Default(logfilter.Warning)
+
+	stop, err := l.HandleSignals("")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer stop()
+
+	wait := func(lvl logfilter.Level) {
+		deadline := time.Now().Add(5 * time.Second)
+		for l.Filters().DefaultLevel() != lvl && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if l.Filters().DefaultLevel() != lvl {
+			t.Fatalf("Default level expected %s, actual %s", logfilter.LevelToString(lvl), logfilter.LevelToString(l.Filters().DefaultLevel()))
+		}
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	wait(logfilter.Info)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	wait(logfilter.Warning)
+
+	//The change is reported at Info even though Info lines are filtered out.
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(b.String(), "\n") < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.HasPrefix(b.String(), "Info: logfilter: SIGUSR1 lowered level, default level Info") {
+		t.Errorf("Notice Mismatch Actual:\"%s\"\n", b.String())
+	}
+}
+
+func TestHandleSignalsReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer os.Setenv(logfilter.SpecEnv, os.Getenv(logfilter.SpecEnv))
+	os.Unsetenv(logfilter.SpecEnv)
+
+	path := filepath.Join(dir, "logfilter.json")
+	if err := ioutil.WriteFile(path, []byte(`{"default": "error", "output": "`+filepath.Join(dir, "out.log")+`"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b syncBuffer
+
+	//A logger with a config file reloads it.
+	cl := logfilter.New(&b, "", 0)
+	stop, err := cl.HandleSignals(path)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer stop()
+
+	//A logger without a config file or spec keeps its rules.
+	var nb syncBuffer
+	l := logfilter.New(&nb, "", 0)
+	l.Include("acme/db").When(logfilter.Debug)
+	l.Default(logfilter.Error)
+	stop, err = l.HandleSignals("")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	defer stop()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cl.Filters().String() != "error" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := cl.Filters().String(); s != "error" {
+		t.Errorf("Reloaded Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "error", s)
+	}
+
+	for !strings.Contains(nb.String(), "\n") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := l.Filters().String(); s != "error,acme/db=debug" {
+		t.Errorf("Kept Rules Mismatch Expected:\"%s\" Actual:\"%s\"\n", "error,acme/db=debug", s)
+	}
+	if !strings.HasPrefix(nb.String(), "Info: logfilter: SIGHUP ignored, rules unchanged") {
+		t.Errorf("Notice Mismatch Actual:\"%s\"\n", nb.String())
+	}
+}
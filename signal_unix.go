@@ -0,0 +1,67 @@
+//go:build !windows && !plan9 && !js && !wasip1
+// +build !windows,!plan9,!js,!wasip1
+
+package logfilter
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+//HandleSignals controls the standard logger with signals.
+//See (*Logger).HandleSignals.
+func HandleSignals(config string) (func(), error) {
+	return std.HandleSignals(config)
+}
+
+//HandleSignals controls the verbosity of the logger with signals:
+//	SIGUSR1  lowers the default level one step, writing out more.
+//	SIGUSR2  raises the default level one step, writing out less.
+//	SIGHUP   reloads the config file, or the LOGFILTER spec if config is "".
+//
+//If config is "" SIGHUP reapplies the LOGFILTER spec as it is set in the
+//environment, normally the one the process was started with, so any rules
+//changed since (i.e. by SIGUSR1 or the Handler) are discarded. If LOGFILTER
+//isn't set either SIGHUP is ignored and the rules are kept.
+//
+//Each change is reported at Info with the new effective configuration. The
+//returned func stops handling the signals.
+func (l *Logger) HandleSignals(config string) (func(), error) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP)
+
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case s := <-c:
+				switch s {
+				case syscall.SIGUSR1:
+					l.step(-1)
+					l.noticeFilter("SIGUSR1 lowered level")
+				case syscall.SIGUSR2:
+					l.step(1)
+					l.noticeFilter("SIGUSR2 raised level")
+				case syscall.SIGHUP:
+					if err := l.reload(config); err != nil {
+						l.notice(Info, "logfilter: SIGHUP ignored, rules unchanged: %v", err)
+						continue
+					}
+					l.noticeFilter("SIGHUP reloaded")
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(stop)
+		})
+	}, nil
+}
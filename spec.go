@@ -0,0 +1,312 @@
+package logfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//SpecEnv is the environment variable read during init. When set its value is
+//applied to the standard filter as a spec (see ApplySpec).
+const SpecEnv = "LOGFILTER"
+
+//ApplySpec replaces the rules of the standard filter with those described by
+//the spec. See (*Filter).ApplySpec for the format.
+func ApplySpec(spec string) error {
+	return stdFilters.ApplySpec(spec)
+}
+
+//Spec returns the rules of the standard filter in the spec format.
+func Spec() string {
+	return stdFilters.String()
+}
+
+//ApplySpec replaces the rules of the filter with those described by the spec.
+//The spec is a comma separated list of elements:
+//	level           sets the default level (Default).
+//	package         includes the package (Include).
+//	package=level   includes the package at the level (Include().When()).
+//	-package        excludes the package (Exclude).
+//	-package=level  excludes the package at the level (Exclude().When()).
+//
+//Where a level follows a package it can also be a band of levels written as
+//min..max (Between), i.e. "-github.com/acme/db=trace..debug". As for Between
+//min and max are swapped if min is the higher level.
+//
+//A message condition can follow the package, which may be empty to match all
+//packages, as a quoted string (IncludeMessage/ExcludeMessage) or a regular
+//expression between slashes (IncludeRegexp/ExcludeRegexp).
+//i.e. `-~"connection reset by peer",github.com/acme/db~/tenant=[0-9]+/=debug`
+//
+//An element can end with a sample (Sample/SamplePercent) and a rate limit
+//(Limit/LimitPackage), each following a semicolon:
+//	;sample=1/n[:by]     keeps 1 in n lines, by is random, callsite or message.
+//	;sample=p%[:by]      keeps the percentage of lines.
+//	;limit=rate/s[:burst][:shared]  rate limits the lines, shared as LimitPackage.
+//i.e. "github.com/acme/db=trace;sample=1/100:callsite;limit=10/s:20"
+//
+//i.e. "warning,github.com/acme/db=debug,-github.com/acme/noisy=fatal"
+//
+//If the spec is invalid an error is returned and the rules are left unchanged.
+func (f *Filter) ApplySpec(spec string) error {
+	r, err := parseSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	f.update(func(rules) rules {
+		return r
+	})
+	return nil
+}
+
+//String returns the rules of the filter in the spec format.
+func (f *Filter) String() string {
+	return f.load().String()
+}
+
+//parseSpec converts the spec into the rules it describes.
+func parseSpec(spec string) (rules, error) {
+	r := defaultRules()
+
+	for _, e := range splitSpec(spec) {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		f, err := parseSpecElement(e)
+		if err != nil {
+			return nil, fmt.Errorf("logfilter: invalid spec element %q: %v", e, err)
+		}
+
+		r = r.set(f)
+	}
+
+	return r, nil
+}
+
+//splitSpec splits the spec into elements at the commas which aren't within a
+//message condition.
+func splitSpec(spec string) []string {
+	var e []string
+
+	for i := 0; i < len(spec); i++ {
+		switch {
+		case spec[i] == ',':
+			e = append(e, spec[:i])
+			spec = spec[i+1:]
+			i = -1
+		case spec[i] == '~' && i+1 < len(spec):
+			if j := closing(spec[i+1:]); j > 0 {
+				i += j + 1
+			}
+		}
+	}
+
+	return append(e, spec)
+}
+
+//closing returns the index of the unescaped quote or slash closing the one s
+//starts with, or -1 if there isn't one.
+func closing(s string) int {
+	if s == "" || (s[0] != '"' && s[0] != '/') {
+		return -1
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case s[0]:
+			return i
+		}
+	}
+	return -1
+}
+
+//parseSpecElement converts a single spec element into a filter.
+func parseSpecElement(e string) (filter, error) {
+	f := newFilter("", true, Undefined)
+	if strings.HasPrefix(e, "-") {
+		f = newFilter("", false, Off)
+		e = e[1:]
+	}
+
+	//The package name runs to the message condition, the level or the options.
+	i := strings.IndexAny(e, "~=;")
+	if i < 0 {
+		i = len(e)
+	}
+	f.find, e = e[:i], e[i:]
+
+	if strings.HasPrefix(e, "~") {
+		j := closing(e[1:])
+		if j < 0 {
+			return f, fmt.Errorf("unterminated message condition")
+		}
+		if err := f.setMessage(e[1 : j+2]); err != nil {
+			return f, err
+		}
+		e = e[j+2:]
+	}
+
+	var opts []string
+	if i := strings.Index(e, ";"); i >= 0 {
+		opts = strings.Split(e[i+1:], ";")
+		e = e[:i]
+	}
+
+	switch {
+	case strings.HasPrefix(e, "="):
+		if err := f.setLevel(e[1:]); err != nil {
+			return f, err
+		}
+	case e != "":
+		return f, fmt.Errorf("unexpected %q", e)
+	case f.inclusive && f.message() == "" && StringToLevel(f.find) != Undefined:
+		//A level on its own sets the default.
+		d := newFilter("", true, StringToLevel(f.find))
+		return d, d.setOptions(opts)
+	}
+
+	if f.find == "" && f.message() == "" {
+		return f, fmt.Errorf("missing package name")
+	}
+	return f, f.setOptions(opts)
+}
+
+//setOptions sets the sample and rate limit of the filter from the options of
+//a spec element, name=value pairs.
+func (f *filter) setOptions(opts []string) error {
+	for _, o := range opts {
+		i := strings.Index(o, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid option %q", o)
+		}
+
+		var err error
+		switch strings.ToLower(strings.TrimSpace(o[:i])) {
+		case "sample":
+			f.sample, err = parseSample(strings.TrimSpace(o[i+1:]))
+		case "limit":
+			f.limit, err = parseLimit(strings.TrimSpace(o[i+1:]))
+		default:
+			err = fmt.Errorf("unknown option %q", o[:i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setMessage sets the message condition of the filter from the spec format,
+//a quoted string or a regular expression between slashes.
+func (f *filter) setMessage(s string) error {
+	if strings.HasPrefix(s, "/") {
+		re, err := regexp.Compile(strings.Replace(s[1:len(s)-1], `\/`, "/", -1))
+		if err != nil {
+			return err
+		}
+		f.re = re
+		return nil
+	}
+
+	c, err := strconv.Unquote(s)
+	if err != nil {
+		return fmt.Errorf("invalid message %s", s)
+	}
+	f.contains = c
+	return nil
+}
+
+//specLevel converts the level name used in a spec to a Level.
+func specLevel(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "undefined") {
+		return Undefined, nil
+	}
+
+	l := StringToLevel(s)
+	if l == Undefined {
+		return Undefined, fmt.Errorf("unknown level %q", s)
+	}
+	return l, nil
+}
+
+//setLevel sets the band of levels the filter applies to from a level, as When,
+//or a band of levels written as min..max, as Between.
+func (f *filter) setLevel(s string) error {
+	i := strings.Index(s, "..")
+	if i < 0 {
+		l, err := specLevel(s)
+		if err != nil {
+			return err
+		}
+		f.when(l)
+		return nil
+	}
+
+	min, err := specLevel(s[:i])
+	if err != nil {
+		return err
+	}
+
+	max, err := specLevel(s[i+2:])
+	if err != nil {
+		return err
+	}
+
+	//As Between, the levels are swapped if min is above max.
+	if rank(min) > rank(max) {
+		min, max = max, min
+	}
+	f.min, f.max = min, max
+	return nil
+}
+
+//String returns the rules in the spec format.
+func (r rules) String() string {
+	var e []string
+
+	for _, f := range r {
+		if f.key() == "" && f.min == Undefined && f.max == Off && f.sample == nil && f.limit == nil {
+			//The default of writing out everything is implied.
+			continue
+		}
+		e = append(e, f.String())
+	}
+
+	return strings.Join(e, ",")
+}
+
+//String returns the filter as a spec element, including its sample and rate
+//limit.
+func (f filter) String() string {
+	s := f.rule()
+	if f.sample != nil {
+		s += ";sample=" + f.sample.String()
+	}
+	if f.limit != nil {
+		s += ";limit=" + f.limit.String()
+	}
+	return s
+}
+
+//rule returns the filter as a spec element without its sample and rate limit.
+func (f filter) rule() string {
+	p := f.key()
+	if !f.inclusive {
+		p = "-" + p
+	}
+
+	switch {
+	case f.find == "" && f.message() == "":
+		return strings.ToLower(f.level())
+	case f.min == Undefined && f.max == Off:
+		return p
+	}
+	return p + "=" + strings.ToLower(f.level())
+}
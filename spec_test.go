@@ -0,0 +1,82 @@
+package logfilter_test
+
+import (
+	"testing"
+
+	"github.com/d2g/logfilter"
+)
+
+func TestApplySpec(t *testing.T) {
+	f := logfilter.NewFilter()
+
+	s := "warning,github.com/acme/db=debug,-github.com/acme/noisy=fatal,github.com/acme/web,-github.com/acme/spam"
+	if err := f.ApplySpec(s); err != nil {
+		t.Fatalf("Unexpected error applying spec %q: %v", s, err)
+	}
+
+	if f.String() != s {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\"\n", s, f.String())
+	}
+
+	tests := []struct {
+		file  string
+		level logfilter.Level
+		out   bool
+	}{
+		{"/src/github.com/acme/api/api.go", logfilter.Info, false},
+		{"/src/github.com/acme/api/api.go", logfilter.Warning, true},
+		{"/src/github.com/acme/db/db.go", logfilter.Debug, true},
+		{"/src/github.com/acme/db/db.go", logfilter.Trace, false},
+		{"/src/github.com/acme/noisy/noisy.go", logfilter.Fatal, false},
+		{"/src/github.com/acme/web/web.go", logfilter.Trace, true},
+		{"/src/github.com/acme/spam/spam.go", logfilter.Fatal, false},
+	}
+
+	for _, test := range tests {
+		l := logfilter.LogLine{File: test.file, Level: test.level}
+		if f.Match(&l) != test.out {
+			t.Errorf("Spec %s line from %s expected %t, actual %t", logfilter.LevelToString(test.level), test.file, test.out, !test.out)
+		}
+	}
+}
+
+func TestApplySpecSampleLimit(t *testing.T) {
+	f := logfilter.NewFilter()
+
+	s := "warning,github.com/acme/db=trace;sample=1/100:callsite;limit=10/s:20,github.com/acme/web;sample=5%;limit=1/s:1:shared"
+	if err := f.ApplySpec(s); err != nil {
+		t.Fatalf("Unexpected error applying spec %q: %v", s, err)
+	}
+
+	if f.String() != s {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\"\n", s, f.String())
+	}
+
+	//The burst defaults to one line.
+	if err := f.ApplySpec("github.com/acme/db;limit=0/s"); err != nil {
+		t.Fatalf("Unexpected error applying spec: %v", err)
+	}
+
+	l := logfilter.LogLine{File: "/src/github.com/acme/db/db.go", Line: 1, Level: logfilter.Info}
+	if !f.Match(&l) || f.Match(&l) {
+		t.Errorf("Spec limit expected to write the first line only")
+	}
+	if f.String() != "github.com/acme/db;limit=0/s:1" {
+		t.Errorf("Spec Mismatch Expected:\"%s\" Actual:\"%s\"\n", "github.com/acme/db;limit=0/s:1", f.String())
+	}
+}
+
+func TestApplySpecInvalid(t *testing.T) {
+	f := logfilter.NewFilter()
+	f.Default(logfilter.Error)
+
+	for _, s := range []string{"acme/db=loud", "=debug", "-", "-=fatal", "acme/db;sample=2", "acme/db;limit=10", "acme/db;limit=10/s:0", "acme/db;burst=1"} {
+		if err := f.ApplySpec(s); err == nil {
+			t.Errorf("Expected error applying spec %q", s)
+		}
+	}
+
+	if f.String() != "error" {
+		t.Errorf("Invalid spec changed the filter expected \"%s\", actual \"%s\"", "error", f.String())
+	}
+}